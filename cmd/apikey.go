@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/spf13/cobra"
+)
+
+// apikeyCmd represents the apikey command
+var apikeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Generates and hashes the API keys of the server clients",
+	Long: `Clients of the serve command authenticate with an API key when server.auth.enabled is set.
+Only the SHA-256 of each key is stored in server.auth.clients[].key_sha256.`,
+}
+
+var apikeyGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generates a new API key and prints it with its hash",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, err := auth.GenerateKey()
+		if err != nil {
+			return err
+		}
+
+		if structuredOutput() {
+			printJSON(struct {
+				Key       string `json:"key"`
+				KeySHA256 string `json:"key_sha256"`
+			}{key, auth.HashKey(key)})
+			return nil
+		}
+
+		fmt.Println("key:        ", key)
+		fmt.Println("key_sha256: ", auth.HashKey(key))
+		fmt.Println("Give the key to the client and add the hash to server.auth.clients, the key is not shown again.")
+		return nil
+	},
+}
+
+var apikeyHashCmd = &cobra.Command{
+	Use:   "hash [key]",
+	Short: "Prints the hash of an existing API key, read from stdin when not given",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var key string
+		if len(args) == 1 {
+			key = args[0]
+		} else {
+			// keeps the key out of the shell history
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return err
+			}
+			key = strings.TrimSpace(line)
+		}
+		if key == "" {
+			return fmt.Errorf("empty key")
+		}
+
+		fmt.Println(auth.HashKey(key))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(apikeyCmd)
+	apikeyCmd.AddCommand(apikeyGenerateCmd)
+	apikeyCmd.AddCommand(apikeyHashCmd)
+}
@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/aisalamdag23/promptme-cli/internal/domain"
-	languagemodels "github.com/aisalamdag23/promptme-cli/internal/usecase/language_models"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -18,7 +17,7 @@ import (
 
 // careerCmd represents the career command
 var careerCmd = &cobra.Command{
-	Use:   "career",
+	Use:   domain.PERSONA_CAREER,
 	Short: "A command-line career coach that provides personalized advice and actionable tips for career growth, leveraging AI to answer prompts and guide users",
 	Long: `This CLI app serves as your personal career coach, designed to offer tailored guidance 
 for professional development. Whether you're exploring new job opportunities, preparing for 
@@ -26,6 +25,8 @@ interviews, or seeking career advice, this tool leverages AI to generate detaile
 responses based on your input. With support for caching, error handling, and real-time 
 interaction, it ensures a smooth and efficient experience for users aiming to achieve their career goals.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		resolveSecrets()
+
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
@@ -39,12 +40,25 @@ interaction, it ensures a smooth and efficient experience for users aiming to ac
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt)
 
+		session, err := openSession(cmd.Name())
+		if err != nil {
+			log.Fatal("opensession.failed:", err)
+		}
+		if len(session.Turns) > 0 && !structuredOutput() {
+			fmt.Printf("Resuming session %s (%d turns): %s\n", session.ID, len(session.Turns), session.Name)
+		}
+
+		done := make(chan struct{})
 		go func() {
-			processPrompt(ctx)
+			processPrompt(ctx, session)
+			close(done)
 		}()
 
-		// Block until signal received
-		<-c
+		// Block until signal received or the user exits
+		select {
+		case <-c:
+		case <-done:
+		}
 		log.Infoln("Shutting down server...")
 
 		_, cancel = context.WithTimeout(ctx, wait)
@@ -52,63 +66,82 @@ interaction, it ensures a smooth and efficient experience for users aiming to ac
 		// Doesn't block if no connections, but will otherwise wait
 		// until the timeout deadline.
 
+		writeMetricsTextfile()
+		flushTraces()
 		log.Infoln("Shutdown complete")
 		os.Exit(0)
 	},
 }
 
+var (
+	sessionID       string
+	continueSession bool
+	readDocument    bool
+)
+
 func init() {
 	rootCmd.AddCommand(careerCmd)
-}
-
-func processPrompt(ctx context.Context) {
-	reader := bufio.NewReader(os.Stdin)
-
-	for { // Continuous loop to accept input
-		fmt.Print("Enter your prompt: ")
-		// read input text
-		strPrompt, _ := reader.ReadString('\n')
-		// for time tracking - start request
-		resp := domain.Response{
-			StartTime: time.Now(),
-		}
 
-		strPrompt = strings.TrimSpace(strPrompt)
-
-		log = loggerWithMetadata(strPrompt)
-
-		log.Debugln("prompt.started.at:", resp.StartTime)
+	careerCmd.Flags().StringVar(&sessionID, "session", "", "resume the session with this id (or unique id prefix)")
+	careerCmd.Flags().BoolVar(&continueSession, "continue", false, "resume the latest session")
+	careerCmd.Flags().BoolVar(&readDocument, "document", false, "when stdin is piped, send all of it as a single prompt instead of one prompt per line")
+}
 
-		// call to llm
-		srv, err := languagemodels.NewGenerator(ctx, cfg, log, inMemCache)
-		if err != nil {
-			log.Fatal("languagemodels.newgenerator.failed:", err)
+// openSession resumes the requested session or starts a new one
+func openSession(persona string) (*domain.Session, error) {
+	switch {
+	case sessionID != "":
+		return sessionStore.Load(sessionID)
+	case continueSession:
+		session, err := sessionStore.Latest(persona)
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return newSession(persona), nil
 		}
-		// generate response - from cache or from llm
-		resp.Text, err = srv.GenerateResponse(ctx, domain.UserPrompt{Text: strPrompt})
-		resp.EndTime = time.Now()
-		if err != nil {
-			fmt.Println("Something went wrong. Try again")
-			continue
-		}
-		// for time tracking - end request and return response
-		resp.ResponseTime = resp.EndTime.Sub(resp.StartTime)
-
-		// logs and debugs
-		log.Debugln("response.returned.at:", resp.ResponseTime)
-		log.Infoln("response.time:", resp.ResponseTime)
-
-		// display response and time
-		fmt.Println("Coach: " + resp.Text)
-		fmt.Println("Response time: " + resp.ResponseTime.String())
+		return session, err
+	default:
+		return newSession(persona), nil
 	}
+}
 
+func newSession(persona string) *domain.Session {
+	now := time.Now()
+	return &domain.Session{
+		ID:        uuid.NewString(),
+		Persona:   persona,
+		Provider:  cfg.LLM.Provider,
+		Model:     cfg.LLM.Gemini.Model,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
 }
 
-func loggerWithMetadata(strPrompt string) *logrus.Entry {
-	reqID, _ := uuid.NewUUID()
+func loggerWithMetadata(reqID, strPrompt string) *logrus.Entry {
 	return log.WithFields(logrus.Fields{
-		"user_prompt": strPrompt,
-		"request_id":  reqID.String(),
+		"user_prompt": redactor.Mask(strPrompt),
+		"request_id":  reqID,
 	})
 }
+
+// responseSummary compact one line summary displayed under each answer
+func responseSummary(resp domain.Response) string {
+	parts := []string{"Response time: " + resp.ResponseTime.String()}
+	if resp.Cached {
+		return strings.Join(append(parts, "cached"), " | ")
+	}
+
+	parts = append(parts, fmt.Sprintf("tokens: %d in / %d out", resp.Usage.PromptTokens, resp.Usage.CompletionTokens))
+	if resp.Cost > 0 {
+		parts = append(parts, fmt.Sprintf("cost: %.6f %s", resp.Cost, cfg.CurrencyCode()))
+	}
+	if resp.FinishReason != "" {
+		parts = append(parts, "finish: "+resp.FinishReason)
+	}
+	if resp.Model != "" {
+		parts = append(parts, "model: "+resp.Model)
+	}
+	for _, rating := range resp.Blocked() {
+		parts = append(parts, "blocked: "+rating.Category)
+	}
+
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/export"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	exportFile   string
+	exportLatest bool
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export [session id]",
+	Short: "Exports a stored session to Markdown, HTML or JSON",
+	Long: `Renders a stored session with its per-turn timestamps, response times and model metadata,
+to share it with a mentor or process it with other tools. The format is inferred from
+the --file extension when --format is not set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var session *domain.Session
+		var err error
+		switch {
+		case len(args) == 1:
+			session, err = sessionStore.Load(args[0])
+		case exportLatest:
+			session, err = sessionStore.Latest(careerCmd.Name())
+		default:
+			return fmt.Errorf("a session id or --latest is required")
+		}
+		if err != nil {
+			return err
+		}
+
+		return exportSession(session, exportFormat, exportFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", "export format: "+strings.Join(export.Formats, ", ")+" (default markdown)")
+	exportCmd.Flags().StringVarP(&exportFile, "file", "o", "", "file to write to (default stdout)")
+	exportCmd.Flags().BoolVar(&exportLatest, "latest", false, "export the latest career session")
+}
+
+// exportSession renders the session to the file, or stdout when empty
+func exportSession(session *domain.Session, format, file string) error {
+	if format == "" {
+		format = export.FormatFromPath(file)
+	}
+	if format == "" {
+		format = export.FormatMarkdown
+	}
+	if !slices.Contains(export.Formats, format) {
+		return fmt.Errorf("unsupported export format %q, expected one of %s", format, strings.Join(export.Formats, ", "))
+	}
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return export.Render(w, session, format)
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+const (
+	promptText        = "Enter your prompt: "
+	continuationText  = "... "
+	multilineDelim    = `"""`
+	historyFileName   = "prompt_history"
+	promptHistorySize = 1000
+	maxBatchLineSize  = 1024 * 1024
+)
+
+// promptReader reads prompts typed by the user
+type promptReader interface {
+	// ReadPrompt returns io.EOF once there is no more input
+	ReadPrompt() (string, error)
+	Close() error
+}
+
+// newPromptReader returns the line editor for a terminal, and reads piped input
+// one prompt per line (or as a single document) otherwise
+func newPromptReader() (promptReader, error) {
+	if !isTerminal(os.Stdin) {
+		return newBatchReader(os.Stdin, readDocument), nil
+	}
+	return newLineEditor()
+}
+
+// isTerminal returns false when f is piped or redirected
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// batchReader reads prompts from non-interactive input
+type batchReader struct {
+	scanner  *bufio.Scanner
+	r        io.Reader
+	document bool
+	done     bool
+}
+
+func newBatchReader(r io.Reader, document bool) promptReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineSize)
+	return &batchReader{
+		scanner:  scanner,
+		r:        r,
+		document: document,
+	}
+}
+
+// ReadPrompt returns the next line, or the whole input once in document mode
+func (b *batchReader) ReadPrompt() (string, error) {
+	if b.document {
+		if b.done {
+			return "", io.EOF
+		}
+		b.done = true
+		data, err := io.ReadAll(b.r)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	if !b.scanner.Scan() {
+		if err := b.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return b.scanner.Text(), nil
+}
+
+func (b *batchReader) Close() error {
+	return nil
+}
+
+// lineEditor readline-style input with arrow keys, history persisted across runs,
+// reverse search (Ctrl+R), tab completion of slash commands and multiline prompts
+// wrapped in triple quotes
+type lineEditor struct {
+	rl *readline.Instance
+}
+
+func newLineEditor() (promptReader, error) {
+	historyFile := filepath.Join(cfg.DataDirPath(), historyFileName)
+	err := os.MkdirAll(filepath.Dir(historyFile), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 promptText,
+		HistoryFile:            historyFile,
+		HistoryLimit:           promptHistorySize,
+		HistorySearchFold:      true,
+		DisableAutoSaveHistory: true,
+		AutoComplete:           slashCompleter(),
+		InterruptPrompt:        "^C",
+		EOFPrompt:              "exit",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &lineEditor{rl: rl}, nil
+}
+
+// ReadPrompt reads a line, or every line up to the closing """ in multiline mode.
+// Ctrl+C clears a partially typed line and ends the conversation on an empty one
+func (e *lineEditor) ReadPrompt() (string, error) {
+	for {
+		line, err := e.rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			if line == "" {
+				return "", err
+			}
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), multilineDelim) {
+			line, err = e.readMultiline(line)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		// history entries are single lines
+		if strings.TrimSpace(line) != "" {
+			_ = e.rl.SaveHistory(strings.Join(strings.Fields(line), " "))
+		}
+		return line, nil
+	}
+}
+
+// readMultiline collects lines until one ends with the closing delimiter
+func (e *lineEditor) readMultiline(first string) (string, error) {
+	text := strings.TrimPrefix(strings.TrimSpace(first), multilineDelim)
+	if strings.HasSuffix(text, multilineDelim) {
+		return strings.TrimSuffix(text, multilineDelim), nil
+	}
+
+	lines := []string{text}
+	e.rl.SetPrompt(continuationText)
+	defer e.rl.SetPrompt(promptText)
+	for {
+		line, err := e.rl.Readline()
+		if err != nil {
+			return "", err
+		}
+		if strings.HasSuffix(strings.TrimSpace(line), multilineDelim) {
+			lines = append(lines, strings.TrimSuffix(strings.TrimSpace(line), multilineDelim))
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+func (e *lineEditor) Close() error {
+	return e.rl.Close()
+}
+
+// slashCompleter completes slash commands, and persona names after /persona
+func slashCompleter() readline.AutoCompleter {
+	items := make([]readline.PrefixCompleterInterface, 0, len(slashCommands))
+	for _, command := range slashCommands {
+		if command.name == "/persona" {
+			items = append(items, readline.PcItem(command.name, readline.PcItemDynamic(personaNames)))
+			continue
+		}
+		items = append(items, readline.PcItem(command.name))
+	}
+	return readline.NewPrefixCompleter(items...)
+}
+
+// personaNames returns the personas available to /persona
+func personaNames(string) []string {
+	return cfg.PersonaNames()
+}
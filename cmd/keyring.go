@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/keyring"
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+)
+
+// keyringCmd represents the keyring command
+var keyringCmd = &cobra.Command{
+	Use:   "keyring",
+	Short: "Manages the secrets of the local encrypted keyring",
+	Long: `Secrets of the keyring are used in the config with keyring:NAME, e.g. api_key: keyring:gemini.
+The keyring is general.keyring.file (keyring.json in the data dir by default), encrypted with the passphrase
+from general.keyring.passphrase or PROMPTME_KEYRING_PASSPHRASE, asked for when neither is set.`,
+}
+
+var keyringSetCmd = &cobra.Command{
+	Use:   "set NAME",
+	Short: "Adds or replaces a secret, read from stdin",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ring, err := openKeyring()
+		if err != nil {
+			return err
+		}
+
+		value, err := readSecret(fmt.Sprintf("Value of %s: ", args[0]))
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("empty secret")
+		}
+
+		err = ring.Set(args[0], value)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Saved %s, use it in the config with %s%s\n", args[0], config.SecretKeyringPrefix, args[0])
+		return nil
+	},
+}
+
+var keyringListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the names of the secrets",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ring, err := openKeyring()
+		if err != nil {
+			return err
+		}
+		names, err := ring.Names()
+		if err != nil {
+			return err
+		}
+
+		if structuredOutput() {
+			printJSON(names)
+			return nil
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	},
+}
+
+var keyringDeleteCmd = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Deletes a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ring, err := openKeyring()
+		if err != nil {
+			return err
+		}
+		return ring.Delete(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(keyringCmd)
+	keyringCmd.AddCommand(keyringSetCmd)
+	keyringCmd.AddCommand(keyringListCmd)
+	keyringCmd.AddCommand(keyringDeleteCmd)
+}
+
+// openKeyring opens the configured keyring, asking for the passphrase in a terminal when it is not configured.
+// A new keyring's passphrase is asked twice
+func openKeyring() (*keyring.Keyring, error) {
+	ring, err := cfg.OpenKeyring()
+	if err == nil || !isTerminal(os.Stdin) {
+		return ring, err
+	}
+
+	passphrase, err := readline.Password("Keyring passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("empty keyring passphrase")
+	}
+	if _, statErr := os.Stat(cfg.KeyringPath()); errors.Is(statErr, os.ErrNotExist) {
+		confirmation, err := readline.Password("Confirm the passphrase of the new keyring: ")
+		if err != nil {
+			return nil, err
+		}
+		if string(confirmation) != string(passphrase) {
+			return nil, fmt.Errorf("the passphrases do not match")
+		}
+	}
+	return keyring.Open(cfg.KeyringPath(), string(passphrase)), nil
+}
+
+// readSecret reads a line from stdin, without echo in a terminal
+func readSecret(prompt string) (string, error) {
+	if isTerminal(os.Stdin) {
+		value, err := readline.Password(prompt)
+		return strings.TrimSpace(string(value)), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
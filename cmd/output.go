@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/markdown"
+	"github.com/chzyer/readline"
+)
+
+const (
+	responseLabel = "Coach:"
+	defaultWidth  = 80
+
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputJSONL = "jsonl"
+)
+
+var (
+	// noColor disables Markdown rendering, also disabled by NO_COLOR or when stdout is not a terminal
+	noColor bool
+	// outputMode text for humans, json (indented) or jsonl (one object per line) for scripts
+	outputMode string
+)
+
+type (
+	// responseOutput a response in json and jsonl output modes
+	responseOutput struct {
+		RequestID      string       `json:"request_id"`
+		SessionID      string       `json:"session_id,omitempty"`
+		Prompt         string       `json:"prompt"`
+		Text           string       `json:"text"`
+		Provider       string       `json:"provider,omitempty"`
+		Model          string       `json:"model,omitempty"`
+		Cached         bool         `json:"cached"`
+		FinishReason   string       `json:"finish_reason,omitempty"`
+		Usage          domain.Usage `json:"usage"`
+		Cost           float64      `json:"cost"`
+		Warnings       []string     `json:"warnings,omitempty"`
+		StartTime      time.Time    `json:"start_time"`
+		EndTime        time.Time    `json:"end_time"`
+		ResponseTimeMs int64        `json:"response_time_ms"`
+	}
+
+	// errorOutput an error in json and jsonl output modes
+	errorOutput struct {
+		RequestID string      `json:"request_id,omitempty"`
+		Error     errorDetail `json:"error"`
+	}
+
+	errorDetail struct {
+		// Type is rejected when a stage refused the request, error otherwise
+		Type    string `json:"type"`
+		Stage   string `json:"stage,omitempty"`
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message"`
+	}
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "print responses as plain text without Markdown rendering")
+	rootCmd.PersistentFlags().StringVar(&outputMode, "output", OutputText, "output format: text, json or jsonl")
+}
+
+// validateOutputMode checks the --output flag
+func validateOutputMode() error {
+	switch outputMode {
+	case OutputText, OutputJSON, OutputJSONL:
+		return nil
+	default:
+		return fmt.Errorf("invalid --output %q, expected text, json or jsonl", outputMode)
+	}
+}
+
+// structuredOutput returns true in json and jsonl output modes
+func structuredOutput() bool {
+	return outputMode == OutputJSON || outputMode == OutputJSONL
+}
+
+// printJSON prints v indented in json mode, on a single line otherwise
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	if outputMode == OutputJSON {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		log.Errorln("printjson.failed:", err)
+	}
+}
+
+func newResponseOutput(sessionID string, userPrompt domain.UserPrompt, resp domain.Response) responseOutput {
+	return responseOutput{
+		RequestID:      userPrompt.RequestID,
+		SessionID:      sessionID,
+		Prompt:         userPrompt.Text,
+		Text:           resp.Text,
+		Provider:       resp.Provider,
+		Model:          resp.Model,
+		Cached:         resp.Cached,
+		FinishReason:   resp.FinishReason,
+		Usage:          resp.Usage,
+		Cost:           resp.Cost,
+		Warnings:       resp.Warnings,
+		StartTime:      resp.StartTime,
+		EndTime:        resp.EndTime,
+		ResponseTimeMs: resp.ResponseTime.Milliseconds(),
+	}
+}
+
+func newErrorOutput(requestID string, err error) errorOutput {
+	var rejection *domain.RejectionError
+	if errors.As(err, &rejection) {
+		return errorOutput{
+			RequestID: requestID,
+			Error: errorDetail{
+				Type:    "rejected",
+				Stage:   rejection.Stage,
+				Reason:  rejection.Reason,
+				Message: rejection.Message,
+			},
+		}
+	}
+	return errorOutput{
+		RequestID: requestID,
+		Error: errorDetail{
+			Type: "error",
+			// provider errors may contain the request url with the api key
+			Message: strings.ReplaceAll(err.Error(), cfg.General.APIKey, "***"),
+		},
+	}
+}
+
+// responseWriter displays a response as it is streamed
+type responseWriter struct {
+	renderer *markdown.Renderer
+	started  bool
+}
+
+func newResponseWriter() *responseWriter {
+	w := &responseWriter{}
+	if colorEnabled() {
+		width := readline.GetScreenWidth()
+		if width <= 0 {
+			width = defaultWidth
+		}
+		w.renderer = markdown.NewRenderer(os.Stdout, width)
+	}
+	return w
+}
+
+// Write displays a chunk, the label is printed with the first one
+func (w *responseWriter) Write(chunk string) {
+	if !w.started {
+		w.started = true
+		if w.renderer != nil {
+			fmt.Println(responseLabel)
+		} else {
+			fmt.Print(responseLabel + " ")
+		}
+	}
+
+	if w.renderer != nil {
+		w.renderer.Write(chunk)
+		return
+	}
+	fmt.Print(chunk)
+}
+
+// Close ends the response, rendering what is left
+func (w *responseWriter) Close() {
+	if w.renderer != nil {
+		w.renderer.Flush()
+		return
+	}
+	if w.started {
+		fmt.Println()
+	}
+}
+
+func colorEnabled() bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+)
+
+func TestNewResponseOutputHasProviderAndModel(t *testing.T) {
+	userPrompt := domain.UserPrompt{Text: "How do I ask for a raise?", RequestID: "req-1"}
+
+	tests := []struct {
+		name string
+		resp domain.Response
+	}{
+		{"generated", domain.Response{Text: "Ask for a meeting.", Provider: "gemini", Model: "gemini-1.5-flash", FinishReason: "STOP"}},
+		{"cached", domain.Response{Text: "Ask for a meeting.", Provider: "gemini", Model: "gemini-1.5-flash", Cached: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(newResponseOutput("session-1", userPrompt, tt.resp))
+			if err != nil {
+				t.Fatalf("json.Marshal: %v", err)
+			}
+
+			var out map[string]interface{}
+			if err := json.Unmarshal(data, &out); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			for _, field := range []string{"request_id", "session_id", "text", "provider", "model", "cached", "usage"} {
+				if _, ok := out[field]; !ok {
+					t.Errorf("output %s has no %q", data, field)
+				}
+			}
+			if out["cached"] != tt.resp.Cached {
+				t.Errorf("cached = %v, want %v", out["cached"], tt.resp.Cached)
+			}
+		})
+	}
+}
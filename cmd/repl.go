@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/tracing"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/conversation"
+	languagemodels "github.com/aisalamdag23/promptme-cli/internal/usecase/language_models"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// repl state of an interactive conversation
+type repl struct {
+	ctx           context.Context
+	session       *domain.Session
+	conversations *conversation.Service
+	// model overrides the configured model, set by /model
+	model string
+	// last prompt and response, used by /retry and /copy-last
+	lastPrompt string
+	lastResp   *domain.Response
+}
+
+func processPrompt(ctx context.Context, session *domain.Session) {
+	reader, err := newPromptReader()
+	if err != nil {
+		log.Fatal("newpromptreader.failed:", err)
+	}
+	defer reader.Close()
+
+	generators := languagemodels.NewGeneratorPool(ctx, cfg, log, inMemCache, usageLedger)
+	historyManager, err := generators.NewHistoryManager()
+	if err != nil {
+		log.Fatal("languagemodels.newhistorymanager.failed:", err)
+	}
+
+	r := &repl{
+		ctx:           ctx,
+		session:       session,
+		conversations: conversation.NewService(cfg, log, generators, historyManager, sessionStore),
+	}
+
+	for { // Continuous loop to accept input
+		// read input text
+		strPrompt, err := reader.ReadPrompt()
+		if err != nil {
+			// end of piped input, Ctrl+D or Ctrl+C on an empty line
+			log.Infoln("reader.readprompt.stopped:", err)
+			return
+		}
+		strPrompt = strings.TrimSpace(strPrompt)
+		if strPrompt == "" {
+			continue
+		}
+
+		// slash commands are handled locally without calling the model
+		if isSlashCommand(strPrompt) {
+			if quit := r.runSlashCommand(strPrompt); quit {
+				return
+			}
+			continue
+		}
+
+		r.ask(strPrompt, false)
+	}
+}
+
+// ask sends the prompt within the session and displays the response
+func (r *repl) ask(strPrompt string, skipCache bool) {
+	reqID := uuid.NewString()
+	log = loggerWithMetadata(reqID, strPrompt)
+
+	log.Debugln("prompt.started.at:", time.Now())
+
+	ctx, span := tracing.Start(r.ctx, "repl.prompt",
+		attribute.String("promptme.request_id", reqID),
+		attribute.String("promptme.session_id", r.session.ID),
+	)
+	defer span.End()
+	ctx = logger.ToContext(ctx, log)
+
+	userPrompt := domain.UserPrompt{
+		Text:      strPrompt,
+		RequestID: reqID,
+		Model:     r.model,
+		SkipCache: skipCache,
+	}
+	r.lastPrompt, r.lastResp = strPrompt, nil
+
+	// display the response as it is generated, structured output is printed once complete
+	out := newResponseWriter()
+	if !structuredOutput() {
+		userPrompt.Stream = out.Write
+	}
+
+	// generate response - from cache or from llm, the turn is added to the session and saved
+	resp, err := r.conversations.Ask(ctx, r.session, userPrompt)
+	out.Close()
+	if err != nil && structuredOutput() {
+		printJSON(newErrorOutput(reqID, err))
+		return
+	}
+	var rejection *domain.RejectionError
+	if errors.As(err, &rejection) {
+		fmt.Println(responseLabel + " " + rejection.Message)
+		return
+	}
+	if err != nil {
+		fmt.Println("Something went wrong. Try again")
+		return
+	}
+	log.Debugln("response.returned.at:", resp.EndTime)
+	r.lastResp = &resp
+
+	if structuredOutput() {
+		printJSON(newResponseOutput(r.session.ID, userPrompt, resp))
+		return
+	}
+
+	// display time, the response was streamed
+	fmt.Println(responseSummary(resp))
+	for _, warning := range resp.Warnings {
+		fmt.Println(warning)
+	}
+}
+
+func (r *repl) save() {
+	err := sessionStore.Save(r.session)
+	if err != nil {
+		log.Errorln("sessionstore.save.failed:", err)
+	}
+}
@@ -4,16 +4,28 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"os"
+	"time"
 
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
 	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/caching"
 	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/ledger"
 	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/metrics"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/sessions"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/tracing"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/billing"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/guardrails"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// flushTracesTimeout bounds the export of the pending spans on exit
+const flushTracesTimeout = 5 * time.Second
+
 // rootCmd represents the base command when called without any subcommands
 var (
 	rootCmd = &cobra.Command{
@@ -30,10 +42,24 @@ Requirements:
 - Log errors, warnings, informative messages for debugging and monitoring
 - Include structural and behavioral design patterns
 - Make integrations to LM APIs swappable`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateOutputMode(); err != nil {
+				return err
+			}
+			initConfig()
+			return nil
+		},
 	}
 	log        *logrus.Entry
 	cfg        *config.Config
 	inMemCache caching.Cache
+	redactor   *guardrails.Redactor
+	// usageLedger records the usage and cost of every provider request
+	usageLedger *billing.Ledger
+	// sessionStore persists conversations so they can be resumed
+	sessionStore domain.SessionStore
+	// shutdownTracing flushes the pending spans
+	shutdownTracing func(context.Context) error
 )
 
 func Execute() {
@@ -43,18 +69,55 @@ func Execute() {
 	}
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
-}
-
 func initConfig() {
+	// until the config is loaded, errors are logged to stderr
+	log = logrus.NewEntry(logrus.StandardLogger())
+
 	var err error
 	cfg, err = config.Load()
 	if err != nil {
 		log.Fatal("config.load.failed:", err)
 	}
 
-	log = logger.NewLogger(cfg.General.LogLevel)
+	log = logger.NewLogger(cfg)
+
+	inMemCache = metrics.InstrumentCache(caching.NewInMemory(cfg))
+	usageLedger = billing.NewLedger(cfg, ledger.NewJSONL(cfg))
+	sessionStore = sessions.NewFileStore(cfg)
+
+	redactor, err = guardrails.NewRedactor(cfg)
+	if err != nil {
+		log.Fatal("guardrails.newredactor.failed:", err)
+	}
+
+	shutdownTracing, err = tracing.Init(context.Background(), cfg)
+	if err != nil {
+		log.Fatal("tracing.init.failed:", err)
+	}
+}
+
+// resolveSecrets resolves the secret references of the config, only the commands calling the provider need them
+func resolveSecrets() {
+	if err := cfg.ResolveSecrets(); err != nil {
+		log.Fatal("config.resolvesecrets.failed:", err)
+	}
+}
+
+// flushTraces exports the pending spans before exiting
+func flushTraces() {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTracesTimeout)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Errorln("tracing.shutdown.failed:", err)
+	}
+}
 
-	inMemCache = caching.NewInMemory(cfg)
+// writeMetricsTextfile writes the metrics of the run when metrics.textfile is set
+func writeMetricsTextfile() {
+	if cfg.Metrics.Textfile == "" {
+		return
+	}
+	if err := metrics.WriteTextfile(cfg.Metrics.Textfile); err != nil {
+		log.Errorln("metrics.writetextfile.failed:", err)
+	}
 }
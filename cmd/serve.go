@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/grpcserver"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/httpserver"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/metrics"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/conversation"
+	languagemodels "github.com/aisalamdag23/promptme-cli/internal/usecase/language_models"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the language model over an HTTP JSON API, or gRPC with --grpc",
+	Long: `Serve the configured language model, with the same cache, guardrails, budgets and rate limit as the CLI:
+
+  POST /v1/generate   {"prompt": "...", "persona": "career", "stream": false}
+
+Responses are streamed as server-sent events when "stream" is true or the request accepts text/event-stream.
+
+With --grpc, the promptme.v1.PromptMe service (api/promptme/v1/promptme.proto) is served instead.
+
+Prometheus metrics are served on GET /metrics, or on metrics.address with --grpc.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		resolveSecrets()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		generators := languagemodels.NewGeneratorPool(ctx, cfg, log, inMemCache, usageLedger)
+		historyManager, err := generators.NewHistoryManager()
+		if err != nil {
+			log.Fatal("languagemodels.newhistorymanager.failed:", err)
+		}
+		conversations := conversation.NewService(cfg, log, generators, historyManager, sessionStore)
+		authenticator, err := auth.NewAuthenticator(cfg, log)
+		if err != nil {
+			log.Fatal("auth.newauthenticator.failed:", err)
+		}
+
+		var server server
+		if serveGRPC {
+			if serveAddr != "" {
+				cfg.Server.GRPCAddress = serveAddr
+			}
+			server = grpcserver.New(cfg, log, generators, conversations, authenticator, redactor)
+		} else {
+			if serveAddr != "" {
+				cfg.Server.Address = serveAddr
+			}
+			server = httpserver.New(cfg, log, generators, conversations, authenticator, redactor)
+		}
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- server.ListenAndServe()
+		}()
+
+		// the http server serves /metrics itself
+		var metricsServer *http.Server
+		if serveGRPC && cfg.Metrics.Address != "" {
+			metricsServer = newMetricsServer(cfg.Metrics.Address)
+			go func() {
+				if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Errorln("metricsserver.listenandserve.failed:", err)
+				}
+			}()
+		}
+
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+		select {
+		case err := <-errc:
+			log.Fatal("server.listenandserve.failed:", err)
+		case <-c:
+		}
+		log.Infoln("Shutting down server...")
+
+		// wait for in-flight requests, streams included, until the deadline
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Duration(cfg.General.ShutdownWaitSec)*time.Second)
+		defer shutdownCancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Warnln("server.shutdown.failed:", err)
+			server.Close()
+		}
+		if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorln("server.listenandserve.failed:", err)
+		}
+		if metricsServer != nil {
+			if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+				log.Warnln("metricsserver.shutdown.failed:", err)
+			}
+		}
+
+		flushTraces()
+		log.Infoln("Shutdown complete")
+	},
+}
+
+// newMetricsServer serves /metrics on addr
+func newMetricsServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// server is served by the serve command until shutdown
+type server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+var (
+	// serveAddr overrides server.address, or server.grpc_address with --grpc
+	serveAddr string
+	serveGRPC bool
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "", "address to listen on, overrides server.address or server.grpc_address")
+	serveCmd.Flags().BoolVar(&serveGRPC, "grpc", false, "serve the gRPC API instead of the HTTP API")
+}
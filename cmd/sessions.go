@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+const sessionTimeFormat = "2006-01-02 15:04"
+
+// sessionsCmd represents the sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Lists, shows, renames and deletes stored conversations",
+	Long: `Every conversation is stored under the data directory so it can be resumed later
+with --session <id> or --continue. Session ids can be shortened to any unique prefix.`,
+}
+
+var sessionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists stored sessions, most recent first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, err := sessionStore.List()
+		if err != nil {
+			return err
+		}
+
+		if structuredOutput() {
+			printJSON(list)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tPERSONA\tTURNS\tUPDATED\tNAME")
+		for _, session := range list {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", session.ID, session.Persona, len(session.Turns), session.UpdatedAt.Local().Format(sessionTimeFormat), session.Name)
+		}
+		return w.Flush()
+	},
+}
+
+var sessionsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Shows the turns of a session",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		session, err := sessionStore.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		if structuredOutput() {
+			printJSON(session)
+			return nil
+		}
+
+		fmt.Printf("Session %s: %s\n", session.ID, session.Name)
+		fmt.Printf("Persona: %s | Model: %s/%s | Created: %s\n\n", session.Persona, session.Provider, session.Model, session.CreatedAt.Local().Format(sessionTimeFormat))
+		for _, turn := range session.Turns {
+			fmt.Printf("[%s] You: %s\n", turn.StartTime.Local().Format(sessionTimeFormat), turn.Prompt)
+			fmt.Printf("Coach: %s\n\n", turn.Response)
+		}
+		return nil
+	},
+}
+
+var sessionsRenameCmd = &cobra.Command{
+	Use:   "rename <id> <name>",
+	Short: "Renames a session",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		session, err := sessionStore.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		session.Name = args[1]
+		return sessionStore.Save(session)
+	},
+}
+
+var sessionsDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Deletes a session",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sessionStore.Delete(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+
+	sessionsCmd.AddCommand(sessionsListCmd)
+	sessionsCmd.AddCommand(sessionsShowCmd)
+	sessionsCmd.AddCommand(sessionsRenameCmd)
+	sessionsCmd.AddCommand(sessionsDeleteCmd)
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/billing"
+)
+
+// slashCommand a REPL command handled locally without calling the model
+type slashCommand struct {
+	name        string
+	args        string
+	description string
+	// run returns true to end the conversation
+	run func(r *repl, args []string) bool
+}
+
+// slashCommands set in init, /help refers to it
+var slashCommands []slashCommand
+
+func init() {
+	slashCommands = []slashCommand{
+		{name: "/help", description: "show this help", run: (*repl).help},
+		{name: "/exit", description: "end the conversation", run: func(*repl, []string) bool { return true }},
+		{name: "/reset", description: "start a new session with an empty history", run: (*repl).reset},
+		{name: "/model", args: "[name]", description: "show or switch the model", run: (*repl).switchModel},
+		{name: "/persona", args: "[name]", description: "show or switch the persona, starts a new session", run: (*repl).switchPersona},
+		{name: "/save", args: "[name]", description: "save the session, optionally renaming it", run: (*repl).saveAs},
+		{name: "/history", description: "show the turns of the session", run: (*repl).showHistory},
+		{name: "/retry", description: "generate a new answer to the last prompt", run: (*repl).retry},
+		{name: "/copy-last", args: "<file>", description: "write the last answer to a file", run: (*repl).copyLast},
+		{name: "/export", args: "<file>", description: "export the session, format from the extension (.md, .html, .json)", run: (*repl).export},
+		{name: "/usage", description: "show the token usage and cost of the session and today", run: (*repl).usage},
+	}
+}
+
+// isSlashCommand plain "exit" is kept for compatibility
+func isSlashCommand(line string) bool {
+	return strings.HasPrefix(line, "/") || strings.EqualFold(line, domain.EXIT)
+}
+
+// runSlashCommand returns true to end the conversation
+func (r *repl) runSlashCommand(line string) bool {
+	fields := strings.Fields(line)
+	name := strings.ToLower(fields[0])
+	if name == domain.EXIT {
+		name = "/exit"
+	}
+
+	for _, command := range slashCommands {
+		if command.name == name {
+			log.WithField("command", name).Infoln("repl.slashcommand.run")
+			return command.run(r, fields[1:])
+		}
+	}
+
+	fmt.Printf("Unknown command %s, type /help for the list of commands\n", name)
+	return false
+}
+
+func (r *repl) help(args []string) bool {
+	for _, command := range slashCommands {
+		fmt.Printf("  %-22s %s\n", strings.TrimSpace(command.name+" "+command.args), command.description)
+	}
+	return false
+}
+
+func (r *repl) reset(args []string) bool {
+	r.session = newSession(r.session.Persona)
+	r.session.Model = r.currentModel()
+	r.lastPrompt, r.lastResp = "", nil
+	fmt.Println("Started a new session")
+	return false
+}
+
+func (r *repl) switchModel(args []string) bool {
+	if len(args) == 0 {
+		fmt.Println("Model: " + r.currentModel())
+		return false
+	}
+
+	r.model = args[0]
+	r.session.Model = args[0]
+	// the session is reloaded before each prompt, unsaved sessions are saved with their first answer
+	if len(r.session.Turns) > 0 {
+		r.save()
+	}
+	fmt.Println("Switched to model " + args[0])
+	return false
+}
+
+func (r *repl) currentModel() string {
+	if r.model != "" {
+		return r.model
+	}
+	return cfg.LLM.Gemini.Model
+}
+
+func (r *repl) switchPersona(args []string) bool {
+	personas := personaNames("")
+	if len(args) == 0 {
+		fmt.Printf("Persona: %s (available: %s)\n", r.session.Persona, strings.Join(personas, ", "))
+		return false
+	}
+
+	persona := args[0]
+	if !slices.Contains(personas, persona) {
+		fmt.Printf("Unknown persona %s (available: %s)\n", persona, strings.Join(personas, ", "))
+		return false
+	}
+
+	r.session = newSession(persona)
+	r.session.Model = r.currentModel()
+	r.lastPrompt, r.lastResp = "", nil
+	fmt.Println("Switched to persona " + persona + " in a new session")
+	return false
+}
+
+func (r *repl) saveAs(args []string) bool {
+	if len(args) > 0 {
+		r.session.Name = strings.Join(args, " ")
+	}
+
+	err := sessionStore.Save(r.session)
+	if err != nil {
+		fmt.Println("Could not save the session: " + err.Error())
+		return false
+	}
+	fmt.Printf("Saved session %s: %s\n", r.session.ID, r.session.Name)
+	return false
+}
+
+func (r *repl) showHistory(args []string) bool {
+	if len(r.session.Turns) == 0 {
+		fmt.Println("No turns yet")
+		return false
+	}
+
+	for i, turn := range r.session.Turns {
+		if i == r.session.SummarizedTurns && i > 0 {
+			fmt.Println("--- turns above are summarized for the model ---")
+		}
+		fmt.Printf("[%s] You: %s\n", turn.StartTime.Local().Format(sessionTimeFormat), turn.Prompt)
+		fmt.Printf("Coach: %s\n\n", turn.Response)
+	}
+	return false
+}
+
+func (r *repl) retry(args []string) bool {
+	if r.lastPrompt == "" {
+		fmt.Println("Nothing to retry")
+		return false
+	}
+
+	// replace the previous answer instead of adding a turn
+	if r.lastResp != nil && len(r.session.Turns) > 0 {
+		r.session.Turns = r.session.Turns[:len(r.session.Turns)-1]
+		r.save()
+	}
+	r.ask(r.lastPrompt, true)
+	return false
+}
+
+func (r *repl) copyLast(args []string) bool {
+	if len(args) != 1 {
+		fmt.Println("Usage: /copy-last <file>")
+		return false
+	}
+	if r.lastResp == nil {
+		fmt.Println("No answer to copy yet")
+		return false
+	}
+
+	err := os.WriteFile(args[0], []byte(r.lastResp.Text+"\n"), 0600)
+	if err != nil {
+		fmt.Println("Could not write the answer: " + err.Error())
+		return false
+	}
+	fmt.Println("Last answer written to " + args[0])
+	return false
+}
+
+func (r *repl) export(args []string) bool {
+	if len(args) != 1 {
+		fmt.Println("Usage: /export <file>")
+		return false
+	}
+
+	err := exportSession(r.session, "", args[0])
+	if err != nil {
+		fmt.Println("Could not export the session: " + err.Error())
+		return false
+	}
+	fmt.Println("Session exported to " + args[0])
+	return false
+}
+
+func (r *repl) usage(args []string) bool {
+	var usage domain.Usage
+	var cost float64
+	for _, turn := range r.session.Turns {
+		usage = usage.Add(turn.Usage)
+		cost += turn.Cost
+	}
+	fmt.Printf("Session: %d turns | tokens: %d in / %d out | cost: %.6f %s\n", len(r.session.Turns), usage.PromptTokens, usage.CompletionTokens, cost, cfg.CurrencyCode())
+
+	year, month, day := time.Now().Date()
+	entries, err := usageLedger.Entries(time.Date(year, month, day, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		fmt.Println("Could not read the usage ledger: " + err.Error())
+		return false
+	}
+	for _, total := range billing.Summarize(entries, billing.GroupByDay) {
+		fmt.Printf("Today: %d requests | tokens: %d in / %d out | cost: %.6f %s\n", total.Requests, total.PromptTokens, total.CompletionTokens, total.Cost, cfg.CurrencyCode())
+	}
+	return false
+}
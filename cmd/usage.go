@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/billing"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageGroupBy string
+	usageSince   string
+)
+
+// usageCmd represents the usage command
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Reports token usage and estimated spend recorded in the local usage ledger",
+	Long: `Reports the token usage and estimated cost of every request sent to a language model provider,
+grouped by day, model, persona or API client. Costs are estimated with the pricing table in the config.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch usageGroupBy {
+		case billing.GroupByDay, billing.GroupByModel, billing.GroupByPersona, billing.GroupByClient:
+		default:
+			return fmt.Errorf("invalid --by %q, expected day, model, persona or client", usageGroupBy)
+		}
+
+		var since time.Time
+		if usageSince != "" {
+			var err error
+			since, err = time.ParseInLocation("2006-01-02", usageSince, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid --since date, expected YYYY-MM-DD: %w", err)
+			}
+		}
+
+		entries, err := usageLedger.Entries(since)
+		if err != nil {
+			log.Errorln("ledger.entries.failed:", err)
+			return err
+		}
+
+		totals := billing.Summarize(entries, usageGroupBy)
+		if structuredOutput() {
+			printJSON(struct {
+				GroupBy  string          `json:"group_by"`
+				Currency string          `json:"currency"`
+				Totals   []billing.Total `json:"totals"`
+			}{usageGroupBy, cfg.CurrencyCode(), totals})
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s\tREQUESTS\tINPUT TOKENS\tOUTPUT TOKENS\tCOST (%s)\n", strings.ToUpper(usageGroupBy), cfg.CurrencyCode())
+
+		var sum billing.Total
+		for _, total := range totals {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.6f\n", total.Key, total.Requests, total.PromptTokens, total.CompletionTokens, total.Cost)
+			sum.Requests += total.Requests
+			sum.PromptTokens += total.PromptTokens
+			sum.CompletionTokens += total.CompletionTokens
+			sum.Cost += total.Cost
+		}
+		fmt.Fprintf(w, "total\t%d\t%d\t%d\t%.6f\n", sum.Requests, sum.PromptTokens, sum.CompletionTokens, sum.Cost)
+
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+
+	usageCmd.Flags().StringVar(&usageGroupBy, "by", billing.GroupByDay, "group totals by day, model, persona or client")
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "only include usage on or after this date (YYYY-MM-DD)")
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "fmt"
+
+// REJECTION_STAGE_BUDGET is the stage of the rejections of the budget guard
+const REJECTION_STAGE_BUDGET = "budget"
+
+// RejectionError is returned when a prompt or a response is refused locally
+// without (or after) calling the language model
+type RejectionError struct {
+	// Stage that refused the request, e.g. topic_guard
+	Stage string
+	// Reason is the machine friendly cause, logged but never shown to the user
+	Reason string
+	// Message is the friendly message shown to the user
+	Message string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%s rejected the request: %s", e.Stage, e.Reason)
+}
@@ -5,6 +5,6 @@ import "context"
 type (
 	// LanguageModelService handles the call to language model
 	LanguageModelService interface {
-		GenerateResponse(ctx context.Context, userPrompt UserPrompt) (string, error)
+		GenerateResponse(ctx context.Context, userPrompt UserPrompt) (Response, error)
 	}
 )
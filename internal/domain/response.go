@@ -2,10 +2,69 @@ package domain
 
 import "time"
 
-// Response response when a user enters a prompt
-type Response struct {
-	Text         string
-	StartTime    time.Time
-	EndTime      time.Time
-	ResponseTime time.Duration
+// finish reasons of a Response, providers map theirs to these
+const (
+	FINISH_REASON_STOP       = "STOP"
+	FINISH_REASON_MAX_TOKENS = "MAX_TOKENS"
+	FINISH_REASON_SAFETY     = "SAFETY"
+	FINISH_REASON_RECITATION = "RECITATION"
+	FINISH_REASON_OTHER      = "OTHER"
+)
+
+type (
+	// Response response when a user enters a prompt
+	Response struct {
+		Text string
+		// Provider that generated the response, e.g. gemini
+		Provider string
+		// Model that generated the response, as requested: the model version is not exposed by the gemini SDK
+		Model string
+		// Cached is true when the response was served from cache without calling the provider
+		Cached bool
+		// FinishReason why the model stopped generating, one of the FINISH_REASON values
+		FinishReason string
+		Usage        Usage
+		// Cost estimated from Usage and the configured pricing
+		Cost          float64
+		SafetyRatings []SafetyRating
+		// Warnings to show the user along with the response, e.g. a budget almost reached
+		Warnings     []string
+		StartTime    time.Time
+		EndTime      time.Time
+		ResponseTime time.Duration
+	}
+
+	// Usage token usage of a generation request
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	}
+
+	// SafetyRating safety rating of the prompt or response for a harm category
+	SafetyRating struct {
+		Category    string `json:"category"`
+		Probability string `json:"probability"`
+		Blocked     bool   `json:"blocked"`
+	}
+)
+
+// Add adds the token counts of other, used when a response needed more than one request
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// Blocked returns the safety ratings that blocked the response
+func (r Response) Blocked() []SafetyRating {
+	var blocked []SafetyRating
+	for _, rating := range r.SafetyRatings {
+		if rating.Blocked {
+			blocked = append(blocked, rating)
+		}
+	}
+	return blocked
 }
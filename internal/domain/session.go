@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// sessionNameMaxLen max length of a session name derived from its first prompt
+const sessionNameMaxLen = 50
+
+// ErrSessionNotFound returned by a SessionStore when no session matches
+var ErrSessionNotFound = errors.New("session not found")
+
+type (
+	// Session a stored conversation with a persona
+	Session struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		Persona   string    `json:"persona"`
+		Provider  string    `json:"provider"`
+		Model     string    `json:"model"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+		Turns     []Turn    `json:"turns"`
+		// Summary of the oldest turns, sent instead of them once the history grows too long
+		Summary string `json:"summary,omitempty"`
+		// SummarizedTurns number of oldest turns covered by Summary
+		SummarizedTurns int `json:"summarized_turns,omitempty"`
+		// Server is set for the sessions started through the server APIs, the only ones they serve
+		Server bool `json:"server,omitempty"`
+		// Client name of the API client that started the session, the only one it is served to
+		Client string `json:"client,omitempty"`
+	}
+
+	// Turn a prompt and its response within a session
+	Turn struct {
+		RequestID    string        `json:"request_id"`
+		Prompt       string        `json:"prompt"`
+		Response     string        `json:"response"`
+		Model        string        `json:"model,omitempty"`
+		Cached       bool          `json:"cached,omitempty"`
+		FinishReason string        `json:"finish_reason,omitempty"`
+		Usage        Usage         `json:"usage"`
+		Cost         float64       `json:"cost,omitempty"`
+		StartTime    time.Time     `json:"start_time"`
+		EndTime      time.Time     `json:"end_time"`
+		ResponseTime time.Duration `json:"response_time"`
+	}
+
+	// TokenCounter counts the tokens of texts as the model would
+	TokenCounter interface {
+		CountTokens(ctx context.Context, texts []string) (int, error)
+	}
+
+	// SessionStore persists sessions
+	SessionStore interface {
+		Save(session *Session) error
+		// Load returns the session with the given id or unique id prefix
+		Load(id string) (*Session, error)
+		// Latest returns the most recently updated session of the persona
+		Latest(persona string) (*Session, error)
+		// List returns all sessions, most recently updated first
+		List() ([]*Session, error)
+		Delete(id string) error
+	}
+)
+
+// ActiveTurns returns the turns not covered by the summary, sent verbatim to the model
+func (s *Session) ActiveTurns() []Turn {
+	if s.SummarizedTurns >= len(s.Turns) {
+		return nil
+	}
+	return s.Turns[s.SummarizedTurns:]
+}
+
+// AddTurn appends the response to the prompt as a new turn
+func (s *Session) AddTurn(prompt UserPrompt, resp Response) {
+	if s.Name == "" {
+		s.Name = truncate(prompt.Text, sessionNameMaxLen)
+	}
+	model := resp.Model
+	if model == "" {
+		model = s.Model
+	}
+	s.Turns = append(s.Turns, Turn{
+		RequestID:    prompt.RequestID,
+		Prompt:       prompt.Text,
+		Response:     resp.Text,
+		Model:        model,
+		Cached:       resp.Cached,
+		FinishReason: resp.FinishReason,
+		Usage:        resp.Usage,
+		Cost:         resp.Cost,
+		StartTime:    resp.StartTime,
+		EndTime:      resp.EndTime,
+		ResponseTime: resp.ResponseTime,
+	})
+	s.UpdatedAt = resp.EndTime
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-3]) + "..."
+}
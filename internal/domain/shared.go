@@ -3,8 +3,47 @@ package domain
 const (
 	EXIT = "exit"
 
-	// LLM_RESPONSE_CONTEXT used to initialize chat session context
-	LLM_RESPONSE_CONTEXT = "only return response that are related to %s and the likes"
+	// LLM_RESPONSE_CONTEXT default system instruction template used to restrict the topic
+	LLM_RESPONSE_CONTEXT = "Only return responses that are related to {{.Keywords}} and the likes. Politely decline anything else."
 
 	LLM_PROVIDER_GEMINI = "gemini"
+
+	// PERSONA_CAREER default persona, served by the career command
+	PERSONA_CAREER = "career"
+
+	// OFF_TOPIC_MESSAGE default message shown when a prompt is rejected by the topic guard
+	OFF_TOPIC_MESSAGE = "Sorry, I can only help with questions related to %s."
+
+	// REFUSAL_MESSAGE default message shown when a response is refused by moderation
+	REFUSAL_MESSAGE = "Sorry, I can't help with that. Please ask something else."
+
+	// SAFETY_BLOCKED_MESSAGE shown when the provider blocked the prompt or response for safety reasons
+	SAFETY_BLOCKED_MESSAGE = "Sorry, the language model blocked this request for safety reasons. Please rephrase it."
+
+	// BUDGET_EXCEEDED_MESSAGE shown when a hard budget limit is reached
+	BUDGET_EXCEEDED_MESSAGE = "Budget exceeded: %s. No request was sent."
+
+	// BUDGET_WARNING_MESSAGE shown when a soft budget threshold is reached
+	BUDGET_WARNING_MESSAGE = "Budget warning: %s."
+
+	// REDACTED_TEXT replaces banned content in a response
+	REDACTED_TEXT = "[redacted]"
+
+	// STRICT_INSTRUCTION appended to the system instruction when retrying a refused response
+	STRICT_INSTRUCTION = "Your previous answer was rejected. Stay strictly on the topic of %s and do not include disallowed content."
+
+	// RESPONSE_JUDGE_PROMPT used to ask the judge model whether a response is compliant
+	RESPONSE_JUDGE_PROMPT = "Answer with YES or NO only. Does the following answer stay on the topic of %s?\n\nAnswer: %s"
+
+	// SUMMARY_PROMPT used to summarize the oldest turns of a long conversation
+	SUMMARY_PROMPT = "Summarize the following conversation between a user and a coach in one compact paragraph. Keep the facts about the user, their goals and the advice already given.\n\n%s"
+
+	// SUMMARY_CONTEXT introduces the summary of earlier turns to the model
+	SUMMARY_CONTEXT = "Summary of our earlier conversation: %s"
+
+	// SUMMARY_ACK model reply to SUMMARY_CONTEXT, keeps the history alternating
+	SUMMARY_ACK = "Understood, I will keep this context in mind."
+
+	// TOPIC_CLASSIFIER_PROMPT used to ask the classifier model whether a prompt is on topic
+	TOPIC_CLASSIFIER_PROMPT = "Answer with YES or NO only. Is the following prompt related to %s?\n\nPrompt: %s"
 )
@@ -3,4 +3,22 @@ package domain
 // UserPrompt user input string
 type UserPrompt struct {
 	Text string
+	// RequestID identifies the request in logs and the usage ledger
+	RequestID string
+	// Persona that received the prompt, e.g. career
+	Persona string
+	// Client is the authenticated API client in server mode, empty for the CLI
+	Client string
+	// Summary of the turns of the conversation older than History
+	Summary string
+	// History previous turns of the conversation, oldest first
+	History []Turn
+	// Model overrides the configured model for this prompt
+	Model string
+	// SkipCache forces a new response even if one is cached, e.g. on retry
+	SkipCache bool
+	// Instruction is sent before the configured system instruction for this prompt only
+	Instruction string
+	// Stream receives the response text as it is generated, nil to only get the full response
+	Stream func(chunk string)
 }
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	ratelimit "github.com/aisalamdag23/promptme-cli/internal/infrastructure/rate_limit"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	keyPrefix = "pm_"
+	keyBytes  = 32
+)
+
+// ErrUnauthenticated returned when the API key is missing or unknown
+var ErrUnauthenticated = errors.New("missing or invalid api key")
+
+type (
+	// Authenticator authenticates the API clients of the servers by their API key
+	Authenticator struct {
+		enabled bool
+		clients []*Client
+	}
+
+	// Client an authenticated API client
+	Client struct {
+		Name    string
+		keyHash []byte
+		// limiter of the client's requests, nil when unlimited
+		limiter *ratelimit.RateLimiter
+	}
+
+	ctxClientMarker struct{}
+)
+
+var ctxClientKey = &ctxClientMarker{}
+
+func NewAuthenticator(cfg *config.Config, log *logrus.Entry) (*Authenticator, error) {
+	a := &Authenticator{enabled: cfg.Server.Auth.Enabled}
+	for _, c := range cfg.Server.Auth.Clients {
+		hash, err := hex.DecodeString(c.KeySHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		client := &Client{Name: c.Name, keyHash: hash}
+		if c.RequestsPerMinute > 0 {
+			limit := rate.Limit(float64(c.RequestsPerMinute) / time.Minute.Seconds())
+			client.limiter = ratelimit.NewRateLimiter(log.WithField("client", c.Name), limit, c.RequestsPerMinute)
+		}
+		a.clients = append(a.clients, client)
+	}
+	return a, nil
+}
+
+// Enabled reports whether requests must be authenticated
+func (a *Authenticator) Enabled() bool {
+	return a.enabled
+}
+
+// Authenticate returns the client of the API key, nil when authentication is disabled
+func (a *Authenticator) Authenticate(key string) (*Client, error) {
+	if !a.enabled {
+		return nil, nil
+	}
+	if key == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	var found *Client
+	// compare with every client so the time taken does not tell which one matched
+	for _, client := range a.clients {
+		if subtle.ConstantTimeCompare(hash[:], client.keyHash) == 1 {
+			found = client
+		}
+	}
+	if found == nil {
+		return nil, ErrUnauthenticated
+	}
+	return found, nil
+}
+
+// Allow reports whether the client may send a request now, always true without a client or limit
+func (c *Client) Allow(ctx context.Context) bool {
+	if c == nil || c.limiter == nil {
+		return true
+	}
+	return c.limiter.Allow(ctx)
+}
+
+// KeyFromHeaders returns the API key of an Authorization: Bearer or X-API-Key header value
+func KeyFromHeaders(authorization, apiKey string) string {
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(apiKey)
+}
+
+// GenerateKey returns a new random API key
+func GenerateKey() (string, error) {
+	b := make([]byte, keyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// HashKey returns the hex encoded SHA-256 of the API key, as configured in server.auth.clients
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// ToContext adds the authenticated client to the context
+func ToContext(ctx context.Context, client *Client) context.Context {
+	return context.WithValue(ctx, ctxClientKey, client)
+}
+
+// FromContext returns the authenticated client of the context, nil if none
+func FromContext(ctx context.Context) *Client {
+	client, _ := ctx.Value(ctxClientKey).(*Client)
+	return client
+}
+
+// ClientName returns the name of the authenticated client of the context, empty if none
+func ClientName(ctx context.Context) string {
+	if client := FromContext(ctx); client != nil {
+		return client.Name
+	}
+	return ""
+}
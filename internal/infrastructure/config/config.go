@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
 	"github.com/go-playground/validator"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
@@ -11,7 +14,14 @@ import (
 
 const (
 	configPathEnvName     = "SPEC_FILE"
+	logFileEnvName        = "LOG_FILE"
+	logFileNameDefault    = "application.log"
 	configFileNameDefault = "./.config.yml"
+	appDirName            = "promptme-cli"
+	currencyDefault       = "USD"
+	serverAddressDefault  = ":8080"
+	grpcAddressDefault    = ":9090"
+	maxBodyBytesDefault   = 1 << 20
 )
 
 type (
@@ -22,26 +32,165 @@ type (
 	// Config ...
 	Config struct {
 		// General ...
-		General General `yaml:"general" validate:"required"`
-		LLM     LLM     `yaml:"llm" validate:"required"`
+		General    General    `yaml:"general" validate:"required"`
+		LLM        LLM        `yaml:"llm" validate:"required"`
+		Guardrails Guardrails `yaml:"guardrails"`
+		Budgets    Budgets    `yaml:"budgets"`
+		History    History    `yaml:"history"`
+		Server     Server     `yaml:"server"`
+		Metrics    Metrics    `yaml:"metrics"`
+		Tracing    Tracing    `yaml:"tracing"`
+		// Personas maps a persona name to its topic restriction
+		Personas map[string]Persona `yaml:"personas"`
+	}
+
+	// Persona config, overrides the llm topic restriction for conversations with the persona
+	Persona struct {
+		Keywords          string `yaml:"keywords"`
+		SystemInstruction string `yaml:"system_instruction"`
 	}
 
 	// General config
 	General struct {
-		// APIKey ...
+		// APIKey is the value or a secret reference: env:NAME, file:PATH, cmd:COMMAND or keyring:NAME
 		APIKey string `yaml:"api_key" validate:"required"`
 		// ShutdownWaitSec is the number of secs the server will wait
 		// before shutting down after it receives an exit signal
 		ShutdownWaitSec int `yaml:"graceful_shutdown_wait_time_sec" validate:"required"`
 		// LogLevel ...
 		LogLevel string `yaml:"log_level" validate:"required"`
+		// DataDir is where local data (usage ledger, sessions, ...) is stored,
+		// defaults to $XDG_DATA_HOME/promptme-cli
+		DataDir string  `yaml:"data_dir"`
+		Log     Log     `yaml:"log"`
+		Keyring Keyring `yaml:"keyring"`
+	}
+
+	// Keyring config of the local encrypted keyring of the keyring: secret references
+	Keyring struct {
+		// File defaults to keyring.json in the data dir
+		File string `yaml:"file"`
+		// Passphrase is a secret reference other than keyring:, defaults to env:PROMPTME_KEYRING_PASSPHRASE
+		Passphrase string `yaml:"passphrase"`
+	}
+
+	// Log config of the log file, rotated once it reaches MaxSizeMB
+	Log struct {
+		// Path of the log file, defaults to $XDG_STATE_HOME/promptme-cli/application.log. LOG_FILE overrides it
+		Path string `yaml:"path"`
+		// Format is json or text, json by default
+		Format string `yaml:"format" validate:"omitempty,oneof=json text"`
+		// MaxSizeMB is the size at which the file is rotated, 100 by default
+		MaxSizeMB int `yaml:"max_size_mb" validate:"gte=0"`
+		// MaxAgeDays after which rotated files are deleted, 0 keeps them
+		MaxAgeDays int `yaml:"max_age_days" validate:"gte=0"`
+		// MaxBackups is the number of rotated files kept, 0 keeps them all
+		MaxBackups int `yaml:"max_backups" validate:"gte=0"`
+		// Compress gzips the rotated files
+		Compress bool `yaml:"compress"`
 	}
 
 	// LLM config
 	LLM struct {
 		Provider string `yaml:"provider" validate:"required"`
 		Keywords string `yaml:"keywords"`
-		Gemini   Gemini `yaml:"gemini" validate:"required"`
+		// SystemInstruction is the template used to restrict responses to Keywords,
+		// {{.Keywords}} is replaced by the configured keywords
+		SystemInstruction string `yaml:"system_instruction"`
+		Gemini            Gemini `yaml:"gemini" validate:"required"`
+		// Currency of the pricing table, only used for display
+		Currency string `yaml:"currency"`
+		// Pricing maps a model name to its token prices
+		Pricing map[string]Price `yaml:"pricing"`
+	}
+
+	// Budgets config for spending and token limits checked before each request
+	Budgets struct {
+		// SoftThreshold is the fraction of a limit (e.g. 0.8) after which a warning is shown
+		SoftThreshold float64       `yaml:"soft_threshold" validate:"omitempty,gt=0,lte=1"`
+		Limits        []BudgetLimit `yaml:"limits" validate:"dive"`
+	}
+
+	// BudgetLimit a token and/or cost limit for a period,
+	// scoped to a provider and/or persona (empty matches all)
+	BudgetLimit struct {
+		Provider string `yaml:"provider"`
+		Persona  string `yaml:"persona"`
+		// Client scopes the limit to the usage of an API client in server mode
+		Client    string  `yaml:"client"`
+		Period    string  `yaml:"period" validate:"required,oneof=daily monthly"`
+		MaxTokens int     `yaml:"max_tokens" validate:"required_without=MaxCost,gte=0"`
+		MaxCost   float64 `yaml:"max_cost" validate:"required_without=MaxTokens,gte=0"`
+	}
+
+	// History config for keeping long conversations within the model's context window
+	History struct {
+		// MaxTokens of history sent with a prompt before the oldest turns are summarized, 0 disables
+		MaxTokens int `yaml:"max_tokens"`
+		// KeepTurns is the number of most recent turns always sent verbatim
+		KeepTurns int `yaml:"keep_turns"`
+		// TokenCounter is provider (the provider's count tokens endpoint) or estimate (local)
+		TokenCounter string `yaml:"token_counter" validate:"omitempty,oneof=provider estimate"`
+		// SummaryModel used to summarize, defaults to the provider's model
+		SummaryModel string `yaml:"summary_model"`
+	}
+
+	// Server config of the serve command
+	Server struct {
+		// Address to listen on, defaults to :8080
+		Address string `yaml:"address"`
+		// GRPCAddress to listen on with serve --grpc, defaults to :9090
+		GRPCAddress string `yaml:"grpc_address"`
+		// MaxBodyBytes is the largest accepted request body or websocket message, defaults to 1 MiB
+		MaxBodyBytes int64 `yaml:"max_body_bytes"`
+		// AllowedOrigins of websocket connections, * allows any, only same-origin when empty
+		AllowedOrigins []string `yaml:"allowed_origins"`
+		Auth           Auth     `yaml:"auth"`
+	}
+
+	// Metrics config of the Prometheus metrics
+	Metrics struct {
+		// Address serves /metrics with serve --grpc, e.g. :9091. The http server always serves /metrics
+		Address string `yaml:"address"`
+		// Textfile is written by the CLI on exit for the node exporter textfile collector, e.g.
+		// /var/lib/node_exporter/textfile/promptme.prom
+		Textfile string `yaml:"textfile"`
+	}
+
+	// Tracing config of the OpenTelemetry traces
+	Tracing struct {
+		// Exporter is none, otlp (OTLP over HTTP) or file (one JSON span per line), none by default
+		Exporter string `yaml:"exporter" validate:"omitempty,oneof=none otlp file"`
+		// Endpoint of the OTLP collector, e.g. localhost:4318, defaults to OTEL_EXPORTER_OTLP_ENDPOINT
+		Endpoint string `yaml:"endpoint"`
+		// Insecure sends the spans to the OTLP endpoint over plain HTTP
+		Insecure bool `yaml:"insecure"`
+		// File written by the file exporter, defaults to traces.jsonl in the data dir
+		File string `yaml:"file"`
+		// SampleRatio of the new traces to record, all by default. Traces sampled by the caller are always recorded
+		SampleRatio float64 `yaml:"sample_ratio" validate:"omitempty,gt=0,lte=1"`
+	}
+
+	// Auth config of the API clients allowed in server mode
+	Auth struct {
+		Enabled bool        `yaml:"enabled"`
+		Clients []APIClient `yaml:"clients" validate:"dive"`
+	}
+
+	// APIClient an API key and the quotas of its client
+	APIClient struct {
+		// Name identifies the client in logs, the usage ledger and budgets.limits
+		Name string `yaml:"name" validate:"required"`
+		// KeySHA256 is the hex encoded SHA-256 of the API key, see the apikey command
+		KeySHA256 string `yaml:"key_sha256" validate:"required,len=64,hexadecimal"`
+		// RequestsPerMinute allowed for the client, 0 for no limit
+		RequestsPerMinute int `yaml:"requests_per_minute"`
+	}
+
+	// Price of a model per million tokens
+	Price struct {
+		InputPerMillion  float64 `yaml:"input_per_million_tokens"`
+		OutputPerMillion float64 `yaml:"output_per_million_tokens"`
 	}
 
 	// Gemini config under llm
@@ -50,9 +199,61 @@ type (
 		// MaxRequestsPerMinute ...
 		MaxRequestsPerMinute int `yaml:"max_requests_per_minute" validate:"required"`
 	}
+
+	// Guardrails config for local checks around the language model
+	Guardrails struct {
+		Redaction  Redaction  `yaml:"redaction"`
+		Topic      TopicGuard `yaml:"topic"`
+		Moderation Moderation `yaml:"moderation"`
+	}
+
+	// Redaction config for masking PII in prompts before they are sent or logged
+	Redaction struct {
+		Enabled bool `yaml:"enabled"`
+		// Detectors are the built-in detectors to use: email, phone, national_id, url.
+		// All of them are used when empty
+		Detectors []string `yaml:"detectors" validate:"dive,oneof=email phone national_id url"`
+		// CustomPatterns maps a placeholder name to a regular expression
+		CustomPatterns map[string]string `yaml:"custom_patterns"`
+		// RestoreResponse puts the original values back in place of placeholders in the response
+		RestoreResponse bool `yaml:"restore_response"`
+	}
+
+	// TopicGuard config for rejecting off-topic prompts before calling the language model,
+	// only applied when llm.keywords is set
+	TopicGuard struct {
+		Enabled bool `yaml:"enabled"`
+		// AllowPatterns are regular expressions, a matching prompt is always accepted
+		AllowPatterns []string `yaml:"allow_patterns"`
+		// DenyPatterns are regular expressions, a matching prompt is always rejected
+		DenyPatterns []string `yaml:"deny_patterns"`
+		// ClassifierModel is an optional cheap model asked when no keyword matches
+		ClassifierModel string `yaml:"classifier_model"`
+		// RejectionMessage is shown to the user when a prompt is rejected
+		RejectionMessage string `yaml:"rejection_message"`
+	}
+
+	// Moderation config for validating responses after generation
+	Moderation struct {
+		Enabled bool `yaml:"enabled"`
+		// Action taken on a failing response: redact, retry or refuse.
+		// Off-topic responses cannot be redacted and are refused instead
+		Action string `yaml:"action" validate:"omitempty,oneof=redact retry refuse"`
+		// MaxRetries is the number of stricter retries for the retry action
+		MaxRetries int `yaml:"max_retries"`
+		// BannedPatterns are regular expressions that must not appear in a response
+		BannedPatterns []string `yaml:"banned_patterns"`
+		// CheckTopic verifies the response mentions at least one of llm.keywords
+		CheckTopic bool `yaml:"check_topic"`
+		// JudgeModel is an optional model asked to confirm topic compliance
+		JudgeModel string `yaml:"judge_model"`
+		// RefusalMessage replaces a refused response
+		RefusalMessage string `yaml:"refusal_message"`
+	}
 )
 
-// Load loads all configurations in to a new Config struct
+// Load loads all configurations in to a new Config struct, its secret references are resolved
+// by ResolveSecrets so that only the commands calling the provider run them
 func Load() (*Config, error) {
 	configFilePath := os.Getenv(configPathEnvName)
 	if configFilePath == "" {
@@ -84,3 +285,119 @@ func Load() (*Config, error) {
 	}
 	return config, nil
 }
+
+// ForPersona returns the config to use for conversations with the persona,
+// personas without config use the llm topic restriction
+func (c *Config) ForPersona(name string) *Config {
+	persona, ok := c.Personas[name]
+	if !ok {
+		return c
+	}
+
+	personaCfg := *c
+	personaCfg.LLM.Keywords = persona.Keywords
+	if persona.SystemInstruction != "" {
+		personaCfg.LLM.SystemInstruction = persona.SystemInstruction
+	}
+	return &personaCfg
+}
+
+// DataDirPath returns the configured data directory or the platform default
+func (c *Config) DataDirPath() string {
+	if c.General.DataDir != "" {
+		return c.General.DataDir
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, appDirName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", "data")
+	}
+	return filepath.Join(home, ".local", "share", appDirName)
+}
+
+// LogFilePath returns the log file from LOG_FILE, the config or the platform default
+func (c *Config) LogFilePath() string {
+	if path := os.Getenv(logFileEnvName); path != "" {
+		return path
+	}
+	if c.General.Log.Path != "" {
+		return c.General.Log.Path
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, appDirName, logFileNameDefault)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".", "log", logFileNameDefault)
+	}
+	return filepath.Join(home, ".local", "state", appDirName, logFileNameDefault)
+}
+
+// CurrencyCode returns the configured pricing currency, USD by default
+func (c *Config) CurrencyCode() string {
+	if c.LLM.Currency != "" {
+		return c.LLM.Currency
+	}
+	return currencyDefault
+}
+
+// ServerAddress returns the configured listen address, :8080 by default
+func (c *Config) ServerAddress() string {
+	if c.Server.Address != "" {
+		return c.Server.Address
+	}
+	return serverAddressDefault
+}
+
+// GRPCServerAddress returns the configured gRPC listen address, :9090 by default
+func (c *Config) GRPCServerAddress() string {
+	if c.Server.GRPCAddress != "" {
+		return c.Server.GRPCAddress
+	}
+	return grpcAddressDefault
+}
+
+// ServerMaxBodyBytes returns the configured request body limit, 1 MiB by default
+func (c *Config) ServerMaxBodyBytes() int64 {
+	if c.Server.MaxBodyBytes > 0 {
+		return c.Server.MaxBodyBytes
+	}
+	return maxBodyBytesDefault
+}
+
+// Models returns the configured model followed by the other priced models, sorted
+func (c *Config) Models() []string {
+	models := []string{c.LLM.Gemini.Model}
+	others := make([]string, 0, len(c.LLM.Pricing))
+	for model := range c.LLM.Pricing {
+		if model != c.LLM.Gemini.Model {
+			others = append(others, model)
+		}
+	}
+	sort.Strings(others)
+	return append(models, others...)
+}
+
+// KnownModel returns whether the model is one of Models, the only ones clients may request
+func (c *Config) KnownModel(model string) bool {
+	return slices.Contains(c.Models(), model)
+}
+
+// PersonaNames returns the career persona and the configured personas, sorted
+func (c *Config) PersonaNames() []string {
+	personas := []string{domain.PERSONA_CAREER}
+	for name := range c.Personas {
+		if name != domain.PERSONA_CAREER {
+			personas = append(personas, name)
+		}
+	}
+	sort.Strings(personas)
+	return personas
+}
+
+// KnownPersona returns whether the persona is one of PersonaNames
+func (c *Config) KnownPersona(persona string) bool {
+	return slices.Contains(c.PersonaNames(), persona)
+}
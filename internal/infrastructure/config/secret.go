@@ -0,0 +1,151 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/keyring"
+	"github.com/pkg/errors"
+)
+
+const (
+	SecretEnvPrefix     = "env:"
+	SecretFilePrefix    = "file:"
+	SecretCmdPrefix     = "cmd:"
+	SecretKeyringPrefix = "keyring:"
+
+	keyringFileNameDefault   = "keyring.json"
+	keyringPassphraseDefault = SecretEnvPrefix + "PROMPTME_KEYRING_PASSPHRASE"
+	// secretCmdTimeout leaves time to answer a prompt of the command, e.g. a gpg pinentry
+	secretCmdTimeout = time.Minute
+)
+
+// ResolveSecrets replaces the secret references of the config by their values,
+// the errors never contain a value
+func (c *Config) ResolveSecrets() error {
+	secrets := []struct {
+		name  string
+		value *string
+	}{
+		{"general.api_key", &c.General.APIKey},
+	}
+	for _, secret := range secrets {
+		value, err := c.resolveSecret(*secret.value)
+		if err != nil {
+			return errors.Wrapf(err, "cannot resolve %s", secret.name)
+		}
+		*secret.value = value
+	}
+	return nil
+}
+
+// resolveSecret returns the value of a secret reference, other values are returned as they are
+func (c *Config) resolveSecret(ref string) (string, error) {
+	var (
+		value string
+		err   error
+	)
+	switch {
+	case strings.HasPrefix(ref, SecretEnvPrefix):
+		name := strings.TrimPrefix(ref, SecretEnvPrefix)
+		value = os.Getenv(name)
+		if value == "" {
+			return "", errors.Errorf("environment variable %s is not set", name)
+		}
+	case strings.HasPrefix(ref, SecretFilePrefix):
+		var data []byte
+		data, err = os.ReadFile(strings.TrimPrefix(ref, SecretFilePrefix))
+		if err != nil {
+			return "", errors.Wrap(err, "cannot read secret file")
+		}
+		value = string(data)
+	case strings.HasPrefix(ref, SecretCmdPrefix):
+		value, err = runSecretCmd(strings.TrimPrefix(ref, SecretCmdPrefix))
+		if err != nil {
+			return "", err
+		}
+	case strings.HasPrefix(ref, SecretKeyringPrefix):
+		var ring *keyring.Keyring
+		ring, err = c.OpenKeyring()
+		if err != nil {
+			return "", err
+		}
+		name := strings.TrimPrefix(ref, SecretKeyringPrefix)
+		value, err = ring.Get(name)
+		if err != nil {
+			return "", errors.Wrapf(err, "keyring secret %s", name)
+		}
+	default:
+		return ref, nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("the secret is empty")
+	}
+	return value, nil
+}
+
+// runSecretCmd returns the output of the command run by the shell, its stderr and, when it is a terminal,
+// its stdin are the CLI's so that it can prompt. The output is left out of the errors
+func runSecretCmd(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCmdTimeout)
+	defer cancel()
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	// piped input is the CLI's own, e.g. the prompts, and must not be consumed by the command
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		cmd.Stdin = os.Stdin
+	}
+	err := cmd.Run()
+	if err != nil {
+		return "", errors.Wrap(err, "secret command failed")
+	}
+	return stdout.String(), nil
+}
+
+// OpenKeyring opens the configured keyring with its resolved passphrase
+func (c *Config) OpenKeyring() (*keyring.Keyring, error) {
+	passphrase, err := c.KeyringPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.Open(c.KeyringPath(), passphrase), nil
+}
+
+// KeyringPath returns the configured keyring file, keyring.json in the data dir by default
+func (c *Config) KeyringPath() string {
+	if c.General.Keyring.File != "" {
+		return c.General.Keyring.File
+	}
+	return filepath.Join(c.DataDirPath(), keyringFileNameDefault)
+}
+
+// KeyringPassphrase resolves the passphrase of the keyring, from PROMPTME_KEYRING_PASSPHRASE by default
+func (c *Config) KeyringPassphrase() (string, error) {
+	ref := c.General.Keyring.Passphrase
+	if ref == "" {
+		ref = keyringPassphraseDefault
+	}
+	if strings.HasPrefix(ref, SecretKeyringPrefix) {
+		return "", errors.New("the keyring passphrase cannot be stored in the keyring")
+	}
+	passphrase, err := c.resolveSecret(ref)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot resolve the keyring passphrase")
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,97 @@
+package grpcserver
+
+import (
+	"time"
+
+	promptmev1 "github.com/aisalamdag23/promptme-cli/api/promptme/v1"
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const errorDomain = "promptme"
+
+func toGenerateResponse(sessionID string, userPrompt domain.UserPrompt, resp domain.Response) *promptmev1.GenerateResponse {
+	return &promptmev1.GenerateResponse{
+		RequestId:      userPrompt.RequestID,
+		SessionId:      sessionID,
+		Persona:        userPrompt.Persona,
+		Text:           resp.Text,
+		Provider:       resp.Provider,
+		Model:          resp.Model,
+		Cached:         resp.Cached,
+		FinishReason:   resp.FinishReason,
+		Usage:          toUsage(resp.Usage),
+		Cost:           resp.Cost,
+		Warnings:       resp.Warnings,
+		StartTime:      toTimestamp(resp.StartTime),
+		EndTime:        toTimestamp(resp.EndTime),
+		ResponseTimeMs: resp.ResponseTime.Milliseconds(),
+	}
+}
+
+func toSession(session *domain.Session) *promptmev1.Session {
+	pb := &promptmev1.Session{
+		Id:              session.ID,
+		Name:            session.Name,
+		Persona:         session.Persona,
+		Provider:        session.Provider,
+		Model:           session.Model,
+		CreatedAt:       toTimestamp(session.CreatedAt),
+		UpdatedAt:       toTimestamp(session.UpdatedAt),
+		Summary:         session.Summary,
+		SummarizedTurns: int32(session.SummarizedTurns),
+	}
+	for _, turn := range session.Turns {
+		pb.Turns = append(pb.Turns, &promptmev1.SessionTurn{
+			RequestId:      turn.RequestID,
+			Prompt:         turn.Prompt,
+			Response:       turn.Response,
+			Model:          turn.Model,
+			Cached:         turn.Cached,
+			FinishReason:   turn.FinishReason,
+			Usage:          toUsage(turn.Usage),
+			Cost:           turn.Cost,
+			StartTime:      toTimestamp(turn.StartTime),
+			EndTime:        toTimestamp(turn.EndTime),
+			ResponseTimeMs: turn.ResponseTime.Milliseconds(),
+		})
+	}
+	return pb
+}
+
+func toUsage(usage domain.Usage) *promptmev1.Usage {
+	return &promptmev1.Usage{
+		PromptTokens:     int32(usage.PromptTokens),
+		CompletionTokens: int32(usage.CompletionTokens),
+		TotalTokens:      int32(usage.TotalTokens),
+	}
+}
+
+func toTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// rejectionStatus budget rejections are resource exhausted, the others failed precondition
+func rejectionStatus(rejection *domain.RejectionError) error {
+	code := codes.FailedPrecondition
+	if rejection.Stage == domain.REJECTION_STAGE_BUDGET {
+		code = codes.ResourceExhausted
+	}
+
+	st := status.New(code, rejection.Message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   rejection.Reason,
+		Domain:   errorDomain,
+		Metadata: map[string]string{"stage": rejection.Stage},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,218 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"time"
+
+	promptmev1 "github.com/aisalamdag23/promptme-cli/api/promptme/v1"
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	requestIDMetadata     = "x-request-id"
+	authorizationMetadata = "authorization"
+	apiKeyMetadata        = "x-api-key"
+)
+
+type (
+	// Server exposes the language model services over gRPC
+	Server struct {
+		promptmev1.UnimplementedPromptMeServer
+
+		cfg           *config.Config
+		log           *logrus.Entry
+		generators    Generators
+		conversations Conversations
+		auth          *auth.Authenticator
+		masker        Masker
+		grpc          *grpc.Server
+	}
+
+	// Generators returns the language model service of a persona
+	Generators interface {
+		Get(persona string) (domain.LanguageModelService, error)
+	}
+
+	// Conversations answers prompts within saved sessions
+	Conversations interface {
+		NewSession(persona, client string) *domain.Session
+		Load(id, client string) (*domain.Session, error)
+		Ask(ctx context.Context, session *domain.Session, userPrompt domain.UserPrompt) (domain.Response, error)
+		Continue(ctx context.Context, id string, userPrompt domain.UserPrompt) (*domain.Session, domain.Response, error)
+	}
+
+	// Masker hides sensitive values of a prompt before it is logged
+	Masker interface {
+		Mask(text string) string
+	}
+
+	ctxRequestIDMarker struct{}
+
+	// contextStream replaces the context of a server stream with the call-scoped one
+	contextStream struct {
+		grpc.ServerStream
+		ctx context.Context
+	}
+)
+
+var ctxRequestIDKey = &ctxRequestIDMarker{}
+
+func New(cfg *config.Config, log *logrus.Entry, generators Generators, conversations Conversations, authenticator *auth.Authenticator, masker Masker) *Server {
+	s := &Server{
+		cfg:           cfg,
+		log:           log,
+		generators:    generators,
+		conversations: conversations,
+		auth:          authenticator,
+		masker:        masker,
+	}
+
+	s.grpc = grpc.NewServer(
+		// the call span is started before the interceptors so that the call logger logs its trace ids
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	promptmev1.RegisterPromptMeServer(s.grpc, s)
+	// lets clients such as grpcurl discover the service
+	reflection.Register(s.grpc)
+	return s
+}
+
+// ListenAndServe serves until Shutdown is called
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.cfg.GRPCServerAddress())
+	if err != nil {
+		return err
+	}
+	s.log.Infoln("grpcserver.listening:", lis.Addr())
+	return s.grpc.Serve(lis)
+}
+
+// Shutdown stops accepting calls and waits for in-flight calls until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close cancels all calls and closes all connections immediately
+func (s *Server) Close() error {
+	s.grpc.Stop()
+	return nil
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, done := s.startCall(ctx, info.FullMethod, func(md metadata.MD) error {
+		return grpc.SetHeader(ctx, md)
+	})
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		done(err)
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	done(err)
+	return resp, err
+}
+
+func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, done := s.startCall(ss.Context(), info.FullMethod, ss.SetHeader)
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		done(err)
+		return err
+	}
+	err = handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	done(err)
+	return err
+}
+
+// authenticate puts the client of the API key in the context, calls without a valid key are unauthenticated
+// and those over the client's rate limit resource exhausted
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	if !s.auth.Enabled() {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := auth.KeyFromHeaders(firstValue(md, authorizationMetadata), firstValue(md, apiKeyMetadata))
+	client, err := s.auth.Authenticate(key)
+	if err != nil {
+		logger.Extract(ctx).Infoln("auth.authenticate.failed:", err)
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	ctx = auth.ToContext(ctx, client)
+	logger.WithField(ctx, "client", client.Name)
+	if !client.Allow(ctx) {
+		return ctx, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return ctx, nil
+}
+
+// startCall assigns a request_id to the call and puts a logger with it in the call context,
+// the returned func logs the end of the call
+func (s *Server) startCall(ctx context.Context, method string, setHeader func(metadata.MD) error) (context.Context, func(error)) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+	reqID := firstValue(md, requestIDMetadata)
+	if reqID == "" {
+		reqID = uuid.NewString()
+	}
+
+	entry := s.log.WithFields(logrus.Fields{
+		"request_id":  reqID,
+		"grpc.method": method,
+	})
+	if err := setHeader(metadata.Pairs(requestIDMetadata, reqID)); err != nil {
+		entry.Debugln("grpcserver.setheader.failed:", err)
+	}
+	ctx = logger.ToContext(ctx, entry)
+	ctx = context.WithValue(ctx, ctxRequestIDKey, reqID)
+
+	return ctx, func(err error) {
+		logger.Extract(ctx).WithFields(logrus.Fields{
+			"grpc.code": status.Code(err).String(),
+			"duration":  time.Since(start).String(),
+		}).Infoln("grpcserver.call.done")
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// requestID returns the request_id assigned by startCall
+func requestID(ctx context.Context) string {
+	reqID, _ := ctx.Value(ctxRequestIDKey).(string)
+	return reqID
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,171 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	promptmev1 "github.com/aisalamdag23/promptme-cli/api/promptme/v1"
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Generate returns the complete response of a prompt
+func (s *Server) Generate(ctx context.Context, req *promptmev1.GenerateRequest) (*promptmev1.GenerateResponse, error) {
+	return s.generate(ctx, req, nil)
+}
+
+// GenerateStream sends the response chunks as they are generated, followed by the complete response
+func (s *Server) GenerateStream(req *promptmev1.GenerateRequest, stream promptmev1.PromptMe_GenerateStreamServer) error {
+	ctx := stream.Context()
+	send := func(chunk string) {
+		err := stream.Send(&promptmev1.GenerateStreamResponse{
+			Event: &promptmev1.GenerateStreamResponse_Chunk{Chunk: chunk},
+		})
+		if err != nil {
+			// the client is gone, the call context is canceled too
+			logger.Extract(ctx).Debugln("grpcserver.send.failed:", err)
+		}
+	}
+
+	resp, err := s.generate(ctx, req, send)
+	if err != nil {
+		return err
+	}
+	return stream.Send(&promptmev1.GenerateStreamResponse{
+		Event: &promptmev1.GenerateStreamResponse_Done{Done: resp},
+	})
+}
+
+// ListModels lists the configured model and the priced models
+func (s *Server) ListModels(ctx context.Context, req *promptmev1.ListModelsRequest) (*promptmev1.ListModelsResponse, error) {
+	resp := &promptmev1.ListModelsResponse{}
+	for _, model := range s.cfg.Models() {
+		resp.Models = append(resp.Models, &promptmev1.Model{
+			Id:       model,
+			Provider: s.cfg.LLM.Provider,
+			Default:  model == s.cfg.LLM.Gemini.Model,
+		})
+	}
+	return resp, nil
+}
+
+// GetSession returns a session the client started
+func (s *Server) GetSession(ctx context.Context, req *promptmev1.GetSessionRequest) (*promptmev1.Session, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	session, err := s.conversations.Load(req.GetId(), auth.ClientName(ctx))
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	return toSession(session), nil
+}
+
+// generate answers the prompt within the requested or a new session, or with the request history
+func (s *Server) generate(ctx context.Context, req *promptmev1.GenerateRequest, stream func(string)) (*promptmev1.GenerateResponse, error) {
+	if strings.TrimSpace(req.GetPrompt()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "prompt is required")
+	}
+	if req.GetSessionId() != "" && req.GetNewSession() {
+		return nil, status.Error(codes.InvalidArgument, "session_id and new_session are exclusive")
+	}
+	if req.GetModel() != "" && !s.cfg.KnownModel(req.GetModel()) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown model %q", req.GetModel())
+	}
+	logger.WithField(ctx, "user_prompt", s.masker.Mask(req.GetPrompt()))
+
+	userPrompt := domain.UserPrompt{
+		Text:      req.GetPrompt(),
+		RequestID: requestID(ctx),
+		Client:    auth.ClientName(ctx),
+		SkipCache: req.GetSkipCache(),
+		Stream:    stream,
+	}
+	// the configured model shares its cache entries with the CLI
+	if req.GetModel() != s.cfg.LLM.Gemini.Model {
+		userPrompt.Model = req.GetModel()
+	}
+
+	if req.GetSessionId() != "" {
+		logger.WithField(ctx, "session_id", req.GetSessionId())
+		session, resp, err := s.conversations.Continue(ctx, req.GetSessionId(), userPrompt)
+		if err != nil {
+			return nil, s.statusError(ctx, err)
+		}
+		userPrompt.Persona = session.Persona
+		return toGenerateResponse(session.ID, userPrompt, resp), nil
+	}
+
+	persona := req.GetPersona()
+	if persona == "" {
+		persona = domain.PERSONA_CAREER
+	}
+	if !s.cfg.KnownPersona(persona) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown persona %q", persona)
+	}
+	logger.WithField(ctx, "persona", persona)
+
+	if req.GetNewSession() {
+		session := s.conversations.NewSession(persona, userPrompt.Client)
+		logger.WithField(ctx, "session_id", session.ID)
+		resp, err := s.conversations.Ask(ctx, session, userPrompt)
+		if err != nil {
+			return nil, s.statusError(ctx, err)
+		}
+		userPrompt.Persona = session.Persona
+		return toGenerateResponse(session.ID, userPrompt, resp), nil
+	}
+
+	srv, err := s.generators.Get(persona)
+	if err != nil {
+		logger.Extract(ctx).Errorln("generators.get.failed:", err)
+		return nil, status.Error(codes.Internal, "the language model is not available")
+	}
+
+	userPrompt.Persona = persona
+	for _, turn := range req.GetHistory() {
+		userPrompt.History = append(userPrompt.History, domain.Turn{Prompt: turn.GetPrompt(), Response: turn.GetResponse()})
+	}
+
+	startTime := time.Now()
+	resp, err := srv.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		return nil, s.statusError(ctx, err)
+	}
+	resp.StartTime = startTime
+	resp.EndTime = time.Now()
+	resp.ResponseTime = resp.EndTime.Sub(resp.StartTime)
+	logger.Extract(ctx).Infoln("response.time:", resp.ResponseTime)
+
+	return toGenerateResponse("", userPrompt, resp), nil
+}
+
+// statusError maps an error to its grpc status, rejections carry their stage and reason as error info.
+// Provider errors are only logged since they may contain the api key
+func (s *Server) statusError(ctx context.Context, err error) error {
+	var rejection *domain.RejectionError
+	if errors.As(err, &rejection) {
+		return rejectionStatus(rejection)
+	}
+
+	log := logger.Extract(ctx)
+	switch {
+	case errors.Is(err, domain.ErrSessionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, context.Canceled):
+		log.Infoln("generateresponse.canceled")
+		return status.Error(codes.Canceled, "the request was canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Warnln("generateresponse.timeout")
+		return status.Error(codes.DeadlineExceeded, "the language model did not respond in time")
+	default:
+		log.Errorln("generateresponse.failed:", err)
+		return status.Error(codes.Unavailable, "the language model request failed")
+	}
+}
@@ -0,0 +1,151 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// scrubbed replaces secrets in the logged urls and bodies
+const scrubbed = "***"
+
+// secretParams are the query parameters whose values are never logged
+var secretParams = []string{"key", "api_key", "access_token"}
+
+// Transport logs every request to an external application with logger.ExternalRequestFieldsBuilder,
+// the fields are logged under external_request.<application> on their own entry, the context fields are left as they are
+type Transport struct {
+	application string
+	log         *logrus.Entry
+	next        http.RoundTripper
+	// secrets are removed from the logged bodies
+	secrets []string
+}
+
+// NewTransport init new logging transport, next sends the requests
+func NewTransport(application string, log *logrus.Entry, next http.RoundTripper, secrets ...string) *Transport {
+	return &Transport{
+		application: application,
+		log:         log,
+		next:        next,
+		secrets:     secrets,
+	}
+}
+
+// RoundTrip sends the request, streamed responses are logged once their body is read or closed
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	fields := logger.NewExternalRequestFieldsBuilder().
+		SetApplication(t.application).
+		SetTime(start).
+		SetRequestMethod(req.Method).
+		SetURL(t.scrubURL(req.URL))
+	if body := t.requestBody(req); body != "" {
+		fields.SetRequestBody(body)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fields.SetRespDuration(time.Since(start))
+		t.done(req, fields, err)
+		return nil, err
+	}
+
+	fields.SetStatusCode(resp.StatusCode)
+	resp.Body = &loggedBody{
+		ReadCloser: resp.Body,
+		done: func(body string) {
+			fields.SetRespDuration(time.Since(start)).
+				SetResponseBody(t.scrub(body))
+			t.done(req, fields, nil)
+		},
+	}
+	return resp, nil
+}
+
+// done logs the request with the context logger, the bodies are not kept on its later log lines
+func (t *Transport) done(req *http.Request, fields logger.ExternalRequestFieldsBuilder, err error) {
+	log := logger.ExtractOr(req.Context(), t.log).WithField("external_request."+t.application, fields.GetFields())
+	if err != nil {
+		log.Warnln("httpclient.request.failed:", t.scrub(err.Error()))
+		return
+	}
+	log.Infoln("httpclient.request.done")
+}
+
+// requestBody returns the start of a replayable request body, the request itself is left unread
+func (t *Transport) requestBody(req *http.Request) string {
+	if req.Body == nil || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(body, logger.LogBodyMaxSize))
+	if err != nil {
+		return ""
+	}
+	return t.scrub(string(b))
+}
+
+func (t *Transport) scrubURL(u *url.URL) string {
+	scrubbedURL := *u
+	query := scrubbedURL.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, scrubbed)
+		}
+	}
+	scrubbedURL.RawQuery = query.Encode()
+	return t.scrub(scrubbedURL.String())
+}
+
+func (t *Transport) scrub(s string) string {
+	for _, secret := range t.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, scrubbed)
+		}
+	}
+	return s
+}
+
+// loggedBody keeps the start of a response body and reports it at the end of the body or on close
+type loggedBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func(body string)
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := logger.LogBodyMaxSize - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish()
+	return err
+}
+
+func (b *loggedBody) finish() {
+	b.once.Do(func() {
+		b.done(b.buf.String())
+	})
+}
@@ -0,0 +1,61 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+)
+
+// withAuth rejects the requests without a valid API key with 401, and those over the client's rate limit with 429.
+// Websocket connections are rate limited per prompt instead, health checks and metrics scrapes need no key
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.auth.Enabled() || r.URL.Path == healthPath || r.URL.Path == metricsPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := auth.KeyFromHeaders(r.Header.Get("Authorization"), r.Header.Get("X-API-Key"))
+		// browsers cannot set headers on websocket connections
+		if key == "" && r.URL.Path == chatWSPath {
+			key = r.URL.Query().Get("api_key")
+		}
+
+		client, err := s.auth.Authenticate(key)
+		if err != nil {
+			logger.Extract(r.Context()).Infoln("auth.authenticate.failed:", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="promptme"`)
+			s.writeAuthError(w, r, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		ctx := auth.ToContext(r.Context(), client)
+		logger.WithField(ctx, "client", client.Name)
+		if r.URL.Path != chatWSPath && !client.Allow(ctx) {
+			s.writeAuthError(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeAuthError writes the error in the OpenAI format on the OpenAI compatible endpoints
+func (s *Server) writeAuthError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	errType := "unauthenticated"
+	if status == http.StatusTooManyRequests {
+		errType = "rate_limited"
+	}
+
+	if r.URL.Path == "/v1/models" || strings.HasPrefix(r.URL.Path, "/v1/chat/completions") {
+		code := "invalid_api_key"
+		if status == http.StatusTooManyRequests {
+			code = "rate_limit_exceeded"
+		}
+		writeOpenAIError(w, status, "invalid_request_error", code, message)
+		return
+	}
+	writeError(w, status, newErrorResponse(requestID(r.Context()), errType, message))
+}
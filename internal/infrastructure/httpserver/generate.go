@@ -0,0 +1,201 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+)
+
+type (
+	// generateRequest body of POST /v1/generate
+	generateRequest struct {
+		Prompt  string `json:"prompt"`
+		Persona string `json:"persona"`
+		// Model overrides the configured model
+		Model string `json:"model"`
+		// History previous turns of the conversation, oldest first
+		History   []historyTurn `json:"history"`
+		SkipCache bool          `json:"skip_cache"`
+		// Stream sends the response as server-sent events, also enabled by Accept: text/event-stream
+		Stream bool `json:"stream"`
+	}
+
+	historyTurn struct {
+		Prompt   string `json:"prompt"`
+		Response string `json:"response"`
+	}
+
+	// generateResponse a response of POST /v1/generate, and the done event of a stream
+	generateResponse struct {
+		RequestID      string       `json:"request_id"`
+		Persona        string       `json:"persona"`
+		Text           string       `json:"text"`
+		Provider       string       `json:"provider,omitempty"`
+		Model          string       `json:"model,omitempty"`
+		Cached         bool         `json:"cached"`
+		FinishReason   string       `json:"finish_reason,omitempty"`
+		Usage          domain.Usage `json:"usage"`
+		Cost           float64      `json:"cost"`
+		Warnings       []string     `json:"warnings,omitempty"`
+		StartTime      time.Time    `json:"start_time"`
+		EndTime        time.Time    `json:"end_time"`
+		ResponseTimeMs int64        `json:"response_time_ms"`
+	}
+
+	// chunkEvent a chunk event of a stream
+	chunkEvent struct {
+		Text string `json:"text"`
+	}
+)
+
+// handleGenerate generates the response of a prompt, as a single json object or as server-sent events
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reqID := requestID(ctx)
+
+	var req generateRequest
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.ServerMaxBodyBytes())
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, newErrorResponse(reqID, "invalid_request", "invalid json body: "+err.Error()))
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, newErrorResponse(reqID, "invalid_request", "prompt is required"))
+		return
+	}
+
+	persona := req.Persona
+	if persona == "" {
+		persona = domain.PERSONA_CAREER
+	}
+	if !s.cfg.KnownPersona(persona) {
+		writeError(w, http.StatusBadRequest, newErrorResponse(reqID, "invalid_request", fmt.Sprintf("unknown persona %q", persona)))
+		return
+	}
+	if req.Model != "" && !s.cfg.KnownModel(req.Model) {
+		writeError(w, http.StatusBadRequest, newErrorResponse(reqID, "invalid_request", fmt.Sprintf("unknown model %q", req.Model)))
+		return
+	}
+
+	logger.WithFields(ctx, map[string]interface{}{
+		"user_prompt": s.masker.Mask(req.Prompt),
+		"persona":     persona,
+	})
+	log := logger.Extract(ctx)
+
+	srv, err := s.generators.Get(persona)
+	if err != nil {
+		log.Errorln("generators.get.failed:", err)
+		writeError(w, http.StatusInternalServerError, newErrorResponse(reqID, "error", "the language model is not available"))
+		return
+	}
+
+	userPrompt := domain.UserPrompt{
+		Text:      req.Prompt,
+		RequestID: reqID,
+		Persona:   persona,
+		Client:    auth.ClientName(ctx),
+		History:   req.turns(),
+		SkipCache: req.SkipCache,
+	}
+	// the configured model shares its cache entries with the CLI
+	if req.Model != s.cfg.LLM.Gemini.Model {
+		userPrompt.Model = req.Model
+	}
+
+	if req.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamResponse(w, r, srv, userPrompt)
+		return
+	}
+
+	startTime := time.Now()
+	resp, err := srv.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		status, body := s.generationError(r.Context(), err)
+		writeError(w, status, body)
+		return
+	}
+	resp.StartTime = startTime
+	resp.EndTime = time.Now()
+	resp.ResponseTime = resp.EndTime.Sub(resp.StartTime)
+	log.Infoln("response.time:", resp.ResponseTime)
+
+	writeJSON(w, http.StatusOK, newGenerateResponse(userPrompt, resp))
+}
+
+// streamResponse sends the chunks of the response as they are generated, followed by a done or error event
+func (s *Server) streamResponse(w http.ResponseWriter, r *http.Request, srv domain.LanguageModelService, userPrompt domain.UserPrompt) {
+	ctx := r.Context()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, newErrorResponse(userPrompt.RequestID, "error", "streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable proxy buffering so chunks are delivered as they are written
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event string, v interface{}) {
+		if err := writeEvent(w, event, v); err != nil {
+			// the client is gone, the request context is canceled too
+			logger.Extract(ctx).Debugln("httpserver.writeevent.failed:", err)
+			return
+		}
+		flusher.Flush()
+	}
+	userPrompt.Stream = func(chunk string) {
+		send("chunk", chunkEvent{Text: chunk})
+	}
+
+	startTime := time.Now()
+	resp, err := srv.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		_, body := s.generationError(r.Context(), err)
+		send("error", body)
+		return
+	}
+	resp.StartTime = startTime
+	resp.EndTime = time.Now()
+	resp.ResponseTime = resp.EndTime.Sub(resp.StartTime)
+	logger.Extract(ctx).Infoln("response.time:", resp.ResponseTime)
+
+	send("done", newGenerateResponse(userPrompt, resp))
+}
+
+// turns converts the request history to session turns
+func (req generateRequest) turns() []domain.Turn {
+	turns := make([]domain.Turn, 0, len(req.History))
+	for _, turn := range req.History {
+		turns = append(turns, domain.Turn{Prompt: turn.Prompt, Response: turn.Response})
+	}
+	return turns
+}
+
+func newGenerateResponse(userPrompt domain.UserPrompt, resp domain.Response) generateResponse {
+	return generateResponse{
+		RequestID:      userPrompt.RequestID,
+		Persona:        userPrompt.Persona,
+		Text:           resp.Text,
+		Provider:       resp.Provider,
+		Model:          resp.Model,
+		Cached:         resp.Cached,
+		FinishReason:   resp.FinishReason,
+		Usage:          resp.Usage,
+		Cost:           resp.Cost,
+		Warnings:       resp.Warnings,
+		StartTime:      resp.StartTime,
+		EndTime:        resp.EndTime,
+		ResponseTimeMs: resp.ResponseTime.Milliseconds(),
+	}
+}
@@ -0,0 +1,356 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+)
+
+const (
+	finishReasonStop          = "stop"
+	finishReasonLength        = "length"
+	finishReasonContentFilter = "content_filter"
+)
+
+type (
+	// chatCompletionRequest body of POST /v1/chat/completions, unsupported OpenAI parameters are ignored
+	chatCompletionRequest struct {
+		Model         string        `json:"model"`
+		Messages      []chatMessage `json:"messages"`
+		Stream        bool          `json:"stream"`
+		StreamOptions struct {
+			IncludeUsage bool `json:"include_usage"`
+		} `json:"stream_options"`
+		// Persona is an extension of the OpenAI format, defaults to career
+		Persona string `json:"persona"`
+	}
+
+	chatMessage struct {
+		Role    string         `json:"role"`
+		Content messageContent `json:"content"`
+	}
+
+	// messageContent is either a string or an array of content parts, only text parts are kept
+	messageContent string
+
+	// chatCompletion a chat.completion or, when streaming, a chat.completion.chunk
+	chatCompletion struct {
+		ID      string        `json:"id"`
+		Object  string        `json:"object"`
+		Created int64         `json:"created"`
+		Model   string        `json:"model"`
+		Choices []chatChoice  `json:"choices"`
+		Usage   *domain.Usage `json:"usage,omitempty"`
+	}
+
+	chatChoice struct {
+		Index        int          `json:"index"`
+		Message      *chatMessage `json:"message,omitempty"`
+		Delta        *chatDelta   `json:"delta,omitempty"`
+		FinishReason *string      `json:"finish_reason"`
+	}
+
+	chatDelta struct {
+		Role    string `json:"role,omitempty"`
+		Content string `json:"content,omitempty"`
+	}
+
+	modelList struct {
+		Object string        `json:"object"`
+		Data   []modelObject `json:"data"`
+	}
+
+	modelObject struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	}
+
+	// openAIError body of the errors of the OpenAI compatible endpoints
+	openAIError struct {
+		Error openAIErrorDetail `json:"error"`
+	}
+
+	openAIErrorDetail struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code,omitempty"`
+	}
+)
+
+func (c *messageContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = messageContent(text)
+		return nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return fmt.Errorf("content must be a string or an array of content parts")
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	*c = messageContent(strings.Join(texts, "\n"))
+	return nil
+}
+
+// handleModels lists the configured model and the priced models
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	models := s.cfg.Models()
+	list := modelList{Object: "list", Data: make([]modelObject, 0, len(models))}
+	for _, model := range models {
+		list.Data = append(list.Data, modelObject{
+			ID:      model,
+			Object:  "model",
+			OwnedBy: s.cfg.LLM.Provider,
+		})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// handleChatCompletions translates an OpenAI chat completion request to the configured provider,
+// topic and moderation rejections are answered with their message and the content_filter finish reason
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reqID := requestID(ctx)
+
+	var req chatCompletionRequest
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.ServerMaxBodyBytes())
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", "invalid json body: "+err.Error())
+		return
+	}
+
+	text, instruction, history, err := fromChatMessages(req.Messages)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+
+	model := req.Model
+	if model == "" {
+		model = s.cfg.LLM.Gemini.Model
+	}
+	if !s.cfg.KnownModel(model) {
+		writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", "model_not_found", fmt.Sprintf("the model %q does not exist", model))
+		return
+	}
+
+	persona := req.Persona
+	if persona == "" {
+		persona = domain.PERSONA_CAREER
+	}
+	if !s.cfg.KnownPersona(persona) {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "", fmt.Sprintf("unknown persona %q", persona))
+		return
+	}
+
+	logger.WithFields(ctx, map[string]interface{}{
+		"user_prompt": s.masker.Mask(text),
+		"persona":     persona,
+	})
+
+	srv, err := s.generators.Get(persona)
+	if err != nil {
+		logger.Extract(ctx).Errorln("generators.get.failed:", err)
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "", "the language model is not available")
+		return
+	}
+
+	userPrompt := domain.UserPrompt{
+		Text:        text,
+		RequestID:   reqID,
+		Persona:     persona,
+		Client:      auth.ClientName(ctx),
+		History:     history,
+		Instruction: instruction,
+	}
+	// the configured model shares its cache entries with the CLI
+	if model != s.cfg.LLM.Gemini.Model {
+		userPrompt.Model = model
+	}
+
+	completion := chatCompletion{
+		ID:      "chatcmpl-" + reqID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+	}
+
+	if req.Stream {
+		s.streamChatCompletion(w, r, srv, userPrompt, completion, req.StreamOptions.IncludeUsage)
+		return
+	}
+
+	startTime := time.Now()
+	resp, err := srv.GenerateResponse(ctx, userPrompt)
+	finishReason := chatFinishReason(resp)
+	if err != nil {
+		var rejection *domain.RejectionError
+		if !errors.As(err, &rejection) || rejection.Stage == domain.REJECTION_STAGE_BUDGET {
+			s.writeChatError(w, r, err)
+			return
+		}
+		resp = domain.Response{Text: rejection.Message}
+		finishReason = finishReasonContentFilter
+	}
+	logger.Extract(ctx).Infoln("response.time:", time.Since(startTime))
+
+	completion.Choices = []chatChoice{{
+		Message:      &chatMessage{Role: "assistant", Content: messageContent(resp.Text)},
+		FinishReason: &finishReason,
+	}}
+	completion.Usage = &resp.Usage
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// streamChatCompletion sends the response as chat.completion.chunk events terminated by [DONE]
+func (s *Server) streamChatCompletion(w http.ResponseWriter, r *http.Request, srv domain.LanguageModelService, userPrompt domain.UserPrompt, chunk chatCompletion, includeUsage bool) {
+	ctx := r.Context()
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "api_error", "", "streaming is not supported")
+		return
+	}
+	chunk.Object = "chat.completion.chunk"
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(v interface{}) {
+		if err := writeData(w, v); err != nil {
+			logger.Extract(ctx).Debugln("httpserver.writedata.failed:", err)
+			return
+		}
+		flusher.Flush()
+	}
+	sendDelta := func(delta chatDelta, finishReason *string) {
+		chunk.Choices = []chatChoice{{Delta: &delta, FinishReason: finishReason}}
+		send(chunk)
+	}
+
+	sendDelta(chatDelta{Role: "assistant"}, nil)
+	userPrompt.Stream = func(text string) {
+		sendDelta(chatDelta{Content: text}, nil)
+	}
+
+	startTime := time.Now()
+	resp, err := srv.GenerateResponse(ctx, userPrompt)
+	finishReason := chatFinishReason(resp)
+	if err != nil {
+		var rejection *domain.RejectionError
+		if !errors.As(err, &rejection) || rejection.Stage == domain.REJECTION_STAGE_BUDGET {
+			_, body := s.chatError(r, err)
+			send(body)
+			return
+		}
+		sendDelta(chatDelta{Content: rejection.Message}, nil)
+		finishReason = finishReasonContentFilter
+	}
+	logger.Extract(ctx).Infoln("response.time:", time.Since(startTime))
+
+	sendDelta(chatDelta{}, &finishReason)
+	if includeUsage {
+		chunk.Choices = []chatChoice{}
+		chunk.Usage = &resp.Usage
+		send(chunk)
+	}
+	if _, err := fmt.Fprint(w, "data: [DONE]\n\n"); err == nil {
+		flusher.Flush()
+	}
+}
+
+// fromChatMessages converts the messages to the prompt, the system instruction and the previous turns,
+// consecutive messages of the same role are joined
+func fromChatMessages(messages []chatMessage) (string, string, []domain.Turn, error) {
+	var (
+		system []string
+		turns  []domain.Turn
+		turn   domain.Turn
+	)
+	for _, message := range messages {
+		content := string(message.Content)
+		switch message.Role {
+		case "system", "developer":
+			system = append(system, content)
+		case "user":
+			if turn.Response != "" {
+				turns = append(turns, turn)
+				turn = domain.Turn{}
+			}
+			turn.Prompt = joinText(turn.Prompt, content)
+		case "assistant":
+			// an answer without a prompt has no turn to belong to
+			if turn.Prompt != "" {
+				turn.Response = joinText(turn.Response, content)
+			}
+		default:
+			return "", "", nil, fmt.Errorf("unsupported message role %q", message.Role)
+		}
+	}
+
+	if strings.TrimSpace(turn.Prompt) == "" || turn.Response != "" {
+		return "", "", nil, fmt.Errorf("the last message must be a user message")
+	}
+	return turn.Prompt, strings.Join(system, "\n\n"), turns, nil
+}
+
+func joinText(text, more string) string {
+	if text == "" {
+		return more
+	}
+	return text + "\n\n" + more
+}
+
+// chatFinishReason maps the provider's finish reason to the OpenAI one
+func chatFinishReason(resp domain.Response) string {
+	switch {
+	case len(resp.Blocked()) > 0:
+		return finishReasonContentFilter
+	case resp.FinishReason == domain.FINISH_REASON_MAX_TOKENS:
+		return finishReasonLength
+	case resp.FinishReason == domain.FINISH_REASON_SAFETY, resp.FinishReason == domain.FINISH_REASON_RECITATION:
+		return finishReasonContentFilter
+	default:
+		return finishReasonStop
+	}
+}
+
+// chatError maps a generation error to its http status and OpenAI error body
+func (s *Server) chatError(r *http.Request, err error) (int, openAIError) {
+	status, body := s.generationError(r.Context(), err)
+	detail := openAIErrorDetail{Message: body.Error.Message, Type: "api_error"}
+	if status == http.StatusTooManyRequests {
+		detail.Type = "insufficient_quota"
+		detail.Code = "insufficient_quota"
+	}
+	return status, openAIError{Error: detail}
+}
+
+func (s *Server) writeChatError(w http.ResponseWriter, r *http.Request, err error) {
+	status, body := s.chatError(r, err)
+	writeJSON(w, status, body)
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, errType, code, message string) {
+	writeJSON(w, status, openAIError{Error: openAIErrorDetail{Message: message, Type: errType, Code: code}})
+}
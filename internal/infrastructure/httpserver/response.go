@@ -0,0 +1,104 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+)
+
+type (
+	// errorResponse body of every error, and the error event of a stream
+	errorResponse struct {
+		RequestID string      `json:"request_id,omitempty"`
+		Error     errorDetail `json:"error"`
+	}
+
+	errorDetail struct {
+		// Type is rejected when a stage refused the request, invalid_request or error otherwise
+		Type    string `json:"type"`
+		Stage   string `json:"stage,omitempty"`
+		Reason  string `json:"reason,omitempty"`
+		Message string `json:"message"`
+	}
+)
+
+func newErrorResponse(requestID, errType, message string) errorResponse {
+	return errorResponse{
+		RequestID: requestID,
+		Error: errorDetail{
+			Type:    errType,
+			Message: message,
+		},
+	}
+}
+
+// generationError maps a generation error to its http status and body,
+// provider errors are only logged since they may contain the api key
+func (s *Server) generationError(ctx context.Context, err error) (int, errorResponse) {
+	reqID := requestID(ctx)
+
+	var rejection *domain.RejectionError
+	if errors.As(err, &rejection) {
+		status := http.StatusUnprocessableEntity
+		if rejection.Stage == domain.REJECTION_STAGE_BUDGET {
+			status = http.StatusTooManyRequests
+		}
+		return status, errorResponse{
+			RequestID: reqID,
+			Error: errorDetail{
+				Type:    "rejected",
+				Stage:   rejection.Stage,
+				Reason:  rejection.Reason,
+				Message: rejection.Message,
+			},
+		}
+	}
+
+	log := logger.Extract(ctx)
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Infoln("generateresponse.canceled")
+		return http.StatusServiceUnavailable, newErrorResponse(reqID, "error", "the request was canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Warnln("generateresponse.timeout")
+		return http.StatusGatewayTimeout, newErrorResponse(reqID, "error", "the language model did not respond in time")
+	default:
+		log.Errorln("generateresponse.failed:", err)
+		return http.StatusBadGateway, newErrorResponse(reqID, "error", "the language model request failed")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, body errorResponse) {
+	writeJSON(w, status, body)
+}
+
+// writeEvent writes a server-sent event with a json payload
+func writeEvent(w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// writeData writes an unnamed server-sent event with a json payload
+func writeData(w http.ResponseWriter, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,201 @@
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/metrics"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	requestIDHeader   = "X-Request-ID"
+	readHeaderTimeout = 10 * time.Second
+
+	healthPath  = "/healthz"
+	metricsPath = "/metrics"
+	chatWSPath  = "/v1/chat/ws"
+)
+
+type (
+	// Server exposes the language model services over HTTP
+	Server struct {
+		cfg           *config.Config
+		log           *logrus.Entry
+		generators    Generators
+		conversations Conversations
+		auth          *auth.Authenticator
+		masker        Masker
+		http          *http.Server
+
+		// closing is closed on shutdown, conns tracks the websocket connections that http.Server does not
+		closing     chan struct{}
+		closingOnce sync.Once
+		conns       sync.WaitGroup
+	}
+
+	// Generators returns the language model service of a persona
+	Generators interface {
+		Get(persona string) (domain.LanguageModelService, error)
+	}
+
+	// Conversations answers prompts within saved sessions
+	Conversations interface {
+		NewSession(persona, client string) *domain.Session
+		Load(id, client string) (*domain.Session, error)
+		Ask(ctx context.Context, session *domain.Session, userPrompt domain.UserPrompt) (domain.Response, error)
+	}
+
+	// Masker hides sensitive values of a prompt before it is logged
+	Masker interface {
+		Mask(text string) string
+	}
+
+	ctxRequestIDMarker struct{}
+)
+
+var ctxRequestIDKey = &ctxRequestIDMarker{}
+
+func New(cfg *config.Config, log *logrus.Entry, generators Generators, conversations Conversations, authenticator *auth.Authenticator, masker Masker) *Server {
+	s := &Server{
+		cfg:           cfg,
+		log:           log,
+		generators:    generators,
+		conversations: conversations,
+		auth:          authenticator,
+		masker:        masker,
+		closing:       make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+healthPath, s.handleHealth)
+	mux.Handle("GET "+metricsPath, metrics.Handler())
+	mux.HandleFunc("POST /v1/generate", s.handleGenerate)
+	// OpenAI compatible
+	mux.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("GET /v1/models", s.handleModels)
+	mux.HandleFunc("GET "+chatWSPath, s.handleChat)
+
+	s.http = &http.Server{
+		Addr: cfg.ServerAddress(),
+		// the request span is started first so that the request logger logs its trace ids
+		Handler:           otelhttp.NewHandler(s.withRequestLogger(s.withAuth(mux)), "httpserver", otelhttp.WithSpanNameFormatter(spanName)),
+		ReadHeaderTimeout: readHeaderTimeout,
+		// no write timeout, streamed responses last as long as the generation
+	}
+	s.http.RegisterOnShutdown(func() {
+		s.closingOnce.Do(func() { close(s.closing) })
+	})
+	return s
+}
+
+// ListenAndServe serves until Shutdown is called, it then returns http.ErrServerClosed
+func (s *Server) ListenAndServe() error {
+	s.log.Infoln("httpserver.listening:", s.http.Addr)
+	return s.http.ListenAndServe()
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests until ctx is done,
+// websocket connections are closed once their in-flight prompt is answered
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.http.Shutdown(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		s.conns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes all connections immediately
+func (s *Server) Close() error {
+	return s.http.Close()
+}
+
+// withRequestLogger assigns a request_id to every request and puts a logger with it in the request context
+func (s *Server) withRequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		reqID := r.Header.Get(requestIDHeader)
+		if reqID == "" {
+			reqID = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, reqID)
+
+		entry := s.log.WithFields(logrus.Fields{
+			"request_id":  reqID,
+			"http.method": r.Method,
+			"http.path":   r.URL.Path,
+		})
+		ctx := logger.ToContext(r.Context(), entry)
+		ctx = context.WithValue(ctx, ctxRequestIDKey, reqID)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		logger.Extract(ctx).WithFields(logrus.Fields{
+			"http.status": rec.status,
+			"duration":    time.Since(start).String(),
+		}).Infoln("httpserver.request.done")
+	})
+}
+
+// requestID returns the request_id assigned by withRequestLogger
+func requestID(ctx context.Context) string {
+	reqID, _ := ctx.Value(ctxRequestIDKey).(string)
+	return reqID
+}
+
+// spanName names the request spans after the method and path, e.g. POST /v1/generate
+func spanName(_ string, r *http.Request) string {
+	return r.Method + " " + r.URL.Path
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// statusRecorder keeps the response status for the request log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streamed responses through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets websocket upgrades through the recorder
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking is not supported")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
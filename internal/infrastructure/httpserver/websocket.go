@@ -0,0 +1,324 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/auth"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+
+	// client messages
+	wsPrompt = "prompt"
+	wsCancel = "cancel"
+
+	// server messages
+	wsSession  = "session"
+	wsChunk    = "chunk"
+	wsDone     = "done"
+	wsError    = "error"
+	wsCanceled = "canceled"
+)
+
+type (
+	// wsClientMessage a message sent by the client
+	wsClientMessage struct {
+		Type      string `json:"type"`
+		Prompt    string `json:"prompt"`
+		Model     string `json:"model"`
+		SkipCache bool   `json:"skip_cache"`
+	}
+
+	// wsServerMessage a message sent to the client, only the fields of its type are set
+	wsServerMessage struct {
+		Type      string            `json:"type"`
+		RequestID string            `json:"request_id,omitempty"`
+		SessionID string            `json:"session_id,omitempty"`
+		Persona   string            `json:"persona,omitempty"`
+		Turns     int               `json:"turns,omitempty"`
+		Text      string            `json:"text,omitempty"`
+		Response  *generateResponse `json:"response,omitempty"`
+		Error     *errorDetail      `json:"error,omitempty"`
+	}
+
+	// wsConn a websocket connection, it is a conversation within a single session
+	// and answers one prompt at a time
+	wsConn struct {
+		s       *Server
+		conn    *websocket.Conn
+		log     *logrus.Entry
+		session *domain.Session
+
+		// writeMu serializes the writes, the connection supports a single writer
+		writeMu sync.Mutex
+
+		// mu guards cancel, the cancel func of the prompt being answered, and the start of a prompt
+		mu       sync.Mutex
+		cancel   context.CancelFunc
+		inFlight sync.WaitGroup
+	}
+)
+
+// handleChat upgrades to a websocket connection that continues the client's session_id query parameter,
+// or starts a new session with the persona query parameter
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reqID := requestID(ctx)
+
+	var session *domain.Session
+	if id := r.URL.Query().Get("session_id"); id != "" {
+		var err error
+		session, err = s.conversations.Load(id, auth.ClientName(ctx))
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			writeError(w, http.StatusNotFound, newErrorResponse(reqID, "invalid_request", err.Error()))
+			return
+		}
+		if err != nil {
+			logger.Extract(ctx).Errorln("conversations.load.failed:", err)
+			writeError(w, http.StatusInternalServerError, newErrorResponse(reqID, "error", "the session could not be loaded"))
+			return
+		}
+	} else {
+		persona := r.URL.Query().Get("persona")
+		if persona == "" {
+			persona = domain.PERSONA_CAREER
+		}
+		if !s.cfg.KnownPersona(persona) {
+			writeError(w, http.StatusBadRequest, newErrorResponse(reqID, "invalid_request", fmt.Sprintf("unknown persona %q", persona)))
+			return
+		}
+		// saved once its first prompt is answered
+		session = s.conversations.NewSession(persona, auth.ClientName(ctx))
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied with the error
+		logger.Extract(ctx).Infoln("websocket.upgrade.failed:", err)
+		return
+	}
+
+	s.conns.Add(1)
+	defer s.conns.Done()
+
+	c := &wsConn{
+		s:       s,
+		conn:    conn,
+		log:     logger.Extract(ctx).WithField("session_id", session.ID),
+		session: session,
+	}
+	c.run(ctx)
+}
+
+// checkOrigin accepts same-origin connections and the configured origins
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range s.cfg.Server.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// run reads the client messages until the connection is closed
+func (c *wsConn) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		// the prompt being answered is canceled with the connection
+		cancel()
+		c.inFlight.Wait()
+		c.conn.Close()
+		c.log.Infoln("websocket.connection.closed")
+	}()
+
+	c.conn.SetReadLimit(c.s.cfg.ServerMaxBodyBytes())
+	_ = c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	go c.keepAlive(ctx)
+
+	c.log.Infoln("websocket.connection.opened")
+	c.send(wsServerMessage{
+		Type:      wsSession,
+		SessionID: c.session.ID,
+		Persona:   c.session.Persona,
+		Turns:     len(c.session.Turns),
+	})
+
+	for {
+		var msg wsClientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.log.Infoln("websocket.read.failed:", err)
+			}
+			return
+		}
+
+		switch msg.Type {
+		case wsPrompt:
+			c.prompt(ctx, msg)
+		case wsCancel:
+			c.cancelPrompt()
+		default:
+			c.sendError("", errorDetail{Type: "invalid_request", Message: fmt.Sprintf("unknown message type %q", msg.Type)})
+		}
+	}
+}
+
+// keepAlive pings the client, and closes the connection on shutdown once the prompt being answered is done
+func (c *wsConn) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				c.log.Debugln("websocket.ping.failed:", err)
+			}
+		case <-c.s.closing:
+			// prompts check closing under mu, none starts once it is released
+			c.mu.Lock()
+			answering := c.cancel != nil
+			c.mu.Unlock()
+			if answering {
+				c.inFlight.Wait()
+			}
+			message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server is shutting down")
+			_ = c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(wsWriteWait))
+			// unblocks the read loop
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// prompt answers the prompt in the background so that it can be canceled
+func (c *wsConn) prompt(ctx context.Context, msg wsClientMessage) {
+	reqID := uuid.NewString()
+	if strings.TrimSpace(msg.Prompt) == "" {
+		c.sendError(reqID, errorDetail{Type: "invalid_request", Message: "prompt is required"})
+		return
+	}
+	if msg.Model != "" && !c.s.cfg.KnownModel(msg.Model) {
+		c.sendError(reqID, errorDetail{Type: "invalid_request", Message: fmt.Sprintf("unknown model %q", msg.Model)})
+		return
+	}
+	if !auth.FromContext(ctx).Allow(ctx) {
+		c.sendError(reqID, errorDetail{Type: "rate_limited", Message: "rate limit exceeded"})
+		return
+	}
+
+	// checked under mu so that keepAlive does not miss a prompt started while closing
+	c.mu.Lock()
+	select {
+	case <-c.s.closing:
+		c.mu.Unlock()
+		c.sendError(reqID, errorDetail{Type: "error", Message: "the server is shutting down"})
+		return
+	default:
+	}
+	if c.cancel != nil {
+		c.mu.Unlock()
+		c.sendError(reqID, errorDetail{Type: "invalid_request", Message: "a prompt is already being answered, cancel it first"})
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	c.inFlight.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.inFlight.Done()
+		defer func() {
+			c.mu.Lock()
+			c.cancel = nil
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		ctx = logger.ToContext(ctx, c.log.WithFields(logrus.Fields{
+			"request_id":  reqID,
+			"user_prompt": c.s.masker.Mask(msg.Prompt),
+		}))
+		ctx = context.WithValue(ctx, ctxRequestIDKey, reqID)
+
+		userPrompt := domain.UserPrompt{
+			Text:      msg.Prompt,
+			RequestID: reqID,
+			Client:    auth.ClientName(ctx),
+			SkipCache: msg.SkipCache,
+			Stream: func(chunk string) {
+				c.send(wsServerMessage{Type: wsChunk, RequestID: reqID, Text: chunk})
+			},
+		}
+		// the configured model shares its cache entries with the CLI
+		if msg.Model != c.s.cfg.LLM.Gemini.Model {
+			userPrompt.Model = msg.Model
+		}
+
+		resp, err := c.s.conversations.Ask(ctx, c.session, userPrompt)
+		if err != nil && ctx.Err() != nil {
+			logger.Extract(ctx).Infoln("websocket.prompt.canceled")
+			c.send(wsServerMessage{Type: wsCanceled, RequestID: reqID})
+			return
+		}
+		if err != nil {
+			_, body := c.s.generationError(ctx, err)
+			c.sendError(reqID, body.Error)
+			return
+		}
+
+		userPrompt.Persona = c.session.Persona
+		out := newGenerateResponse(userPrompt, resp)
+		c.send(wsServerMessage{Type: wsDone, RequestID: reqID, SessionID: c.session.ID, Response: &out})
+	}()
+}
+
+// cancelPrompt cancels the prompt being answered, if any
+func (c *wsConn) cancelPrompt() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+func (c *wsConn) sendError(reqID string, detail errorDetail) {
+	c.send(wsServerMessage{Type: wsError, RequestID: reqID, Error: &detail})
+}
+
+func (c *wsConn) send(msg wsServerMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		// the client is gone, the read loop cancels the prompt
+		c.log.Debugln("websocket.write.failed:", err)
+	}
+}
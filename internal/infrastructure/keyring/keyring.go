@@ -0,0 +1,240 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	fileVersion = 1
+	kdfScrypt   = "scrypt"
+
+	// scrypt parameters recommended for interactive logins
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	keyLen  = 32
+	saltLen = 16
+)
+
+var (
+	// ErrNotFound the keyring has no secret with the name
+	ErrNotFound = errors.New("secret not found in keyring")
+	// ErrPassphrase the keyring cannot be decrypted with the passphrase
+	ErrPassphrase = errors.New("wrong keyring passphrase or corrupted keyring file")
+)
+
+type (
+	// Keyring stores named secrets in a local file encrypted with a passphrase,
+	// AES-256-GCM with a key derived by scrypt
+	Keyring struct {
+		path       string
+		passphrase string
+		mu         sync.Mutex
+	}
+
+	// file is the json content of the keyring file, the secrets are encrypted together
+	file struct {
+		Version    int    `json:"version"`
+		KDF        kdf    `json:"kdf"`
+		Nonce      []byte `json:"nonce"`
+		Ciphertext []byte `json:"ciphertext"`
+	}
+
+	kdf struct {
+		Name string `json:"name"`
+		Salt []byte `json:"salt"`
+		N    int    `json:"n"`
+		R    int    `json:"r"`
+		P    int    `json:"p"`
+	}
+)
+
+// Open init the keyring stored in path, the file is created by the first Set
+func Open(path, passphrase string) *Keyring {
+	return &Keyring{
+		path:       path,
+		passphrase: passphrase,
+	}
+}
+
+// Get returns the secret with the name, ErrNotFound if there is none
+func (k *Keyring) Get(name string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// Set adds or replaces the secret with the name
+func (k *Keyring) Set(name, value string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.load()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return k.save(secrets)
+}
+
+// Delete removes the secret with the name, ErrNotFound if there is none
+func (k *Keyring) Delete(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return ErrNotFound
+	}
+	delete(secrets, name)
+	return k.save(secrets)
+}
+
+// Names returns the names of the secrets, sorted
+func (k *Keyring) Names() ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	secrets, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load decrypts the secrets, a missing file is an empty keyring
+func (k *Keyring) load() (map[string]string, error) {
+	data, err := os.ReadFile(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read keyring file")
+	}
+
+	var f file
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse keyring file")
+	}
+	if f.Version != fileVersion || f.KDF.Name != kdfScrypt {
+		return nil, errors.Errorf("unsupported keyring file version %d", f.Version)
+	}
+
+	gcm, err := k.cipher(f.KDF)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != gcm.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	plaintext, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+
+	secrets := map[string]string{}
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return secrets, nil
+}
+
+// save encrypts the secrets with a new salt and nonce and replaces the file atomically
+func (k *Keyring) save(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return errors.Wrap(err, "cannot encode keyring")
+	}
+
+	f := file{
+		Version: fileVersion,
+		KDF:     kdf{Name: kdfScrypt, Salt: make([]byte, saltLen), N: scryptN, R: scryptR, P: scryptP},
+	}
+	_, err = rand.Read(f.KDF.Salt)
+	if err != nil {
+		return errors.Wrap(err, "cannot generate keyring salt")
+	}
+	gcm, err := k.cipher(f.KDF)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	_, err = rand.Read(f.Nonce)
+	if err != nil {
+		return errors.Wrap(err, "cannot generate keyring nonce")
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot encode keyring file")
+	}
+
+	err = os.MkdirAll(filepath.Dir(k.path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "cannot create keyring directory")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), filepath.Base(k.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "cannot create keyring file")
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot write keyring file")
+	}
+	// CreateTemp already creates the file with 0600
+	err = os.Rename(tmp.Name(), k.path)
+	if err != nil {
+		return errors.Wrap(err, "cannot replace keyring file")
+	}
+	return nil
+}
+
+func (k *Keyring) cipher(params kdf) (cipher.AEAD, error) {
+	if k.passphrase == "" {
+		return nil, errors.New("empty keyring passphrase")
+	}
+	key, err := scrypt.Key([]byte(k.passphrase), params.Salt, params.N, params.R, params.P, keyLen)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot derive keyring key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,107 @@
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/pkg/errors"
+)
+
+const fileName = "usage.jsonl"
+
+type (
+	// Entry a single provider request in the ledger
+	Entry struct {
+		Time             time.Time `json:"time"`
+		RequestID        string    `json:"request_id"`
+		Persona          string    `json:"persona"`
+		Client           string    `json:"client,omitempty"`
+		Provider         string    `json:"provider"`
+		Model            string    `json:"model"`
+		PromptTokens     int       `json:"prompt_tokens"`
+		CompletionTokens int       `json:"completion_tokens"`
+		TotalTokens      int       `json:"total_tokens"`
+		Cost             float64   `json:"cost"`
+		Currency         string    `json:"currency"`
+	}
+
+	// Ledger stores the usage of every provider request
+	Ledger interface {
+		Append(entry Entry) error
+		// Entries returns the entries recorded at or after since
+		Entries(since time.Time) ([]Entry, error)
+	}
+
+	// JSONLLedger appends entries as JSON lines to a local file
+	JSONLLedger struct {
+		path string
+		mu   sync.Mutex
+	}
+)
+
+// NewJSONL init new ledger stored in the data directory
+func NewJSONL(cfg *config.Config) Ledger {
+	return &JSONLLedger{
+		path: filepath.Join(cfg.DataDirPath(), fileName),
+	}
+}
+
+// Append appends the entry to the ledger file
+func (l *JSONLLedger) Append(entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := os.MkdirAll(filepath.Dir(l.path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "cannot create ledger directory")
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "cannot open ledger file")
+	}
+	defer f.Close()
+
+	err = json.NewEncoder(f).Encode(entry)
+	if err != nil {
+		return errors.Wrap(err, "cannot write ledger entry")
+	}
+	return nil
+}
+
+// Entries reads the entries recorded at or after since
+func (l *JSONLLedger) Entries(since time.Time) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open ledger file")
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry Entry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read ledger entry")
+		}
+		if !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "cannot read ledger file")
+	}
+	return entries, nil
+}
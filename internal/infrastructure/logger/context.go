@@ -35,18 +35,30 @@ func ToContext(ctx context.Context, entry *logrus.Entry) context.Context {
 func Extract(ctx context.Context) *logrus.Entry {
 	if ctx == nil {
 		return logrus.
-			NewEntry(newLogger())
+			NewEntry(defaultLogger())
 	}
 	l := extract(ctx)
 	if l == nil {
 		return logrus.
-			NewEntry(newLogger())
+			NewEntry(defaultLogger())
 	}
 	return l.logger.
 		WithContext(ctx).
 		WithFields(l.fields)
 }
 
+// ExtractOr takes the call-scoped logrus.Entry from context,
+// falling back to the given entry when the context has none.
+func ExtractOr(ctx context.Context, fallback *logrus.Entry) *logrus.Entry {
+	if extract(ctx) == nil {
+		if ctx == nil {
+			return fallback
+		}
+		return fallback.WithContext(ctx)
+	}
+	return Extract(ctx)
+}
+
 // WithFields adds logrus fields to the logger inside context.
 func WithFields(ctx context.Context, fields logrus.Fields) {
 	l := extract(ctx)
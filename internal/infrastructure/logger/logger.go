@@ -4,48 +4,109 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sync"
 
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// TimestampFormat common timestamp format for the package
-const TimestampFormat = "2006-01-02T15:04:05.000"
+const (
+	// TimestampFormat common timestamp format for the package
+	TimestampFormat = "2006-01-02T15:04:05.000"
 
-// NewLogger creates a new logrus entry
-func NewLogger(logLevel string) *logrus.Entry {
-	logger := newLogger()
-	level, err := logrus.ParseLevel(logLevel)
+	FormatJSON = "json"
+	FormatText = "text"
+
+	maxSizeMBDefault = 100
+)
+
+var (
+	// std is the logger created by NewLogger, used by Extract for contexts without a logger
+	std   *logrus.Logger
+	stdMu sync.RWMutex
+)
+
+// NewLogger creates a new logrus entry writing to the configured log file
+func NewLogger(cfg *config.Config) *logrus.Entry {
+	logger := newLogger(getLogOutput(cfg), cfg.General.Log.Format)
+	level, err := logrus.ParseLevel(cfg.General.LogLevel)
 	if err == nil {
 		logger.Level = level
 	}
+
+	stdMu.Lock()
+	std = logger
+	stdMu.Unlock()
 	return logrus.NewEntry(logger)
 }
 
-func newLogger() *logrus.Logger {
+// defaultLogger returns the logger created by NewLogger, or one writing to stderr before the config is loaded
+func defaultLogger() *logrus.Logger {
+	stdMu.RLock()
+	defer stdMu.RUnlock()
+	if std != nil {
+		return std
+	}
+	return newLogger(os.Stderr, FormatJSON)
+}
+
+func newLogger(out io.Writer, format string) *logrus.Logger {
 	// Initiate logging configurations
 	logger := logrus.New()
-	logger.Out = getLogOutput()
+	logger.Out = out
 	logger.Level = logrus.InfoLevel
-	logger.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: TimestampFormat,
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "level",
-			logrus.FieldKeyMsg:   "message",
-		},
-	})
+	fieldMap := logrus.FieldMap{
+		logrus.FieldKeyTime:  "timestamp",
+		logrus.FieldKeyLevel: "level",
+		logrus.FieldKeyMsg:   "message",
+	}
+	if format == FormatText {
+		logger.SetFormatter(&logrus.TextFormatter{
+			TimestampFormat: TimestampFormat,
+			FullTimestamp:   true,
+			DisableColors:   true,
+			FieldMap:        fieldMap,
+		})
+	} else {
+		logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: TimestampFormat,
+			FieldMap:        fieldMap,
+		})
+	}
+	logger.AddHook(traceHook{})
 	return logger
 }
 
-func getLogOutput() (f io.Writer) {
-	logFile := "log/application.log"
-	if os.Getenv("LOG_FILE") != "" {
-		logFile = os.Getenv("LOG_FILE")
+// getLogOutput returns the rotated log file, or stderr when it cannot be written
+// so that logs never end up in the responses printed to stdout
+func getLogOutput(cfg *config.Config) io.Writer {
+	logFile := cfg.LogFilePath()
+	err := os.MkdirAll(filepath.Dir(logFile), 0700)
+	if err == nil {
+		// the file is opened by the rotator on the first write, check that it can be
+		var f *os.File
+		f, err = os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err == nil {
+			f.Close()
+		}
 	}
-	f, err := os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE, 0755)
 	if err != nil {
-		fmt.Printf("error creating %s file, falling back to STDOUT\n", logFile)
-		f = os.Stdout
+		fmt.Fprintf(os.Stderr, "cannot open log file %s, logging to stderr: %v\n", logFile, err)
+		return os.Stderr
+	}
+
+	maxSize := cfg.General.Log.MaxSizeMB
+	if maxSize == 0 {
+		maxSize = maxSizeMBDefault
+	}
+	return &lumberjack.Logger{
+		Filename:   logFile,
+		MaxSize:    maxSize,
+		MaxAge:     cfg.General.Log.MaxAgeDays,
+		MaxBackups: cfg.General.Log.MaxBackups,
+		Compress:   cfg.General.Log.Compress,
+		LocalTime:  true,
 	}
-	return
 }
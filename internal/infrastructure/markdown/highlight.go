@@ -0,0 +1,43 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// codeKeywords common keywords across the languages a coach may answer with
+var codeKeywords = map[string]struct{}{
+	"func": {}, "function": {}, "def": {}, "class": {}, "return": {}, "if": {}, "else": {},
+	"elif": {}, "for": {}, "while": {}, "switch": {}, "case": {}, "break": {}, "continue": {},
+	"import": {}, "from": {}, "package": {}, "const": {}, "var": {}, "let": {}, "type": {},
+	"struct": {}, "interface": {}, "public": {}, "private": {}, "static": {}, "new": {},
+	"try": {}, "catch": {}, "except": {}, "finally": {}, "throw": {}, "raise": {},
+	"true": {}, "false": {}, "nil": {}, "null": {}, "None": {}, "True": {}, "False": {},
+	"select": {}, "where": {}, "insert": {}, "update": {}, "delete": {},
+	"SELECT": {}, "FROM": {}, "WHERE": {}, "INSERT": {}, "UPDATE": {}, "DELETE": {},
+}
+
+// codeTokenPattern matches comments, strings, words and anything else, in that order
+var codeTokenPattern = regexp.MustCompile(`//.*$|#.*$|"(?:[^"\\]|\\.)*"?|'(?:[^'\\]|\\.)*'?|[A-Za-z_][A-Za-z0-9_]*|\d+(?:\.\d+)?|.`)
+
+// highlight colors comments, strings, numbers and keywords of a code line
+func highlight(line string) string {
+	var b strings.Builder
+	for _, token := range codeTokenPattern.FindAllString(line, -1) {
+		switch {
+		case strings.HasPrefix(token, "//") || strings.HasPrefix(token, "#"):
+			b.WriteString(gray + token + fgOff)
+		case strings.HasPrefix(token, `"`) || strings.HasPrefix(token, "'"):
+			b.WriteString(green + token + fgOff)
+		case token[0] >= '0' && token[0] <= '9':
+			b.WriteString(yellow + token + fgOff)
+		default:
+			if _, ok := codeKeywords[token]; ok {
+				b.WriteString(magenta + token + fgOff)
+				continue
+			}
+			b.WriteString(token)
+		}
+	}
+	return b.String()
+}
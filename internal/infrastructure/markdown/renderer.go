@@ -0,0 +1,176 @@
+package markdown
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used by the renderer
+const (
+	reset        = "\x1b[0m"
+	bold         = "\x1b[1m"
+	boldOff      = "\x1b[22m"
+	italic       = "\x1b[3m"
+	italicOff    = "\x1b[23m"
+	underline    = "\x1b[4m"
+	underlineOff = "\x1b[24m"
+	fgOff        = "\x1b[39m"
+	magenta      = "\x1b[35m"
+	cyan         = "\x1b[36m"
+	yellow       = "\x1b[33m"
+	green        = "\x1b[32m"
+	blue         = "\x1b[34m"
+	gray         = "\x1b[90m"
+
+	codeIndent   = "  "
+	minWidth     = 20
+	fence        = "```"
+	bulletSymbol = "•"
+)
+
+var (
+	ansiPattern    = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletPattern  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	quotePattern   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+
+	inlineCodePattern = regexp.MustCompile("`([^`]+)`")
+	boldPattern       = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicPattern     = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*|(^|[^_\w])_([^_\s][^_]*)_`)
+	linkPattern       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// Renderer formats Markdown with ANSI styles for a terminal, line by line,
+// so it can render a streamed response incrementally
+type Renderer struct {
+	w       io.Writer
+	width   int
+	pending string
+	inCode  bool
+}
+
+// NewRenderer init new renderer word-wrapping to width columns
+func NewRenderer(w io.Writer, width int) *Renderer {
+	if width < minWidth {
+		width = minWidth
+	}
+	return &Renderer{
+		w:     w,
+		width: width,
+	}
+}
+
+// Write renders every complete line of the chunk, the last partial line is kept until more text arrives
+func (r *Renderer) Write(chunk string) {
+	text := r.pending + chunk
+	lines := strings.Split(text, "\n")
+	r.pending = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		r.renderLine(line)
+	}
+}
+
+// Flush renders the remaining partial line
+func (r *Renderer) Flush() {
+	if r.pending != "" {
+		r.renderLine(r.pending)
+		r.pending = ""
+	}
+	if r.inCode {
+		r.inCode = false
+		io.WriteString(r.w, reset)
+	}
+}
+
+func (r *Renderer) renderLine(line string) {
+	if strings.HasPrefix(strings.TrimSpace(line), fence) {
+		r.inCode = !r.inCode
+		io.WriteString(r.w, gray+codeIndent+strings.Repeat("─", 3)+fgOff+"\n")
+		return
+	}
+	if r.inCode {
+		io.WriteString(r.w, codeIndent+highlight(line)+reset+"\n")
+		return
+	}
+
+	if m := headingPattern.FindStringSubmatch(line); m != nil {
+		color := bold
+		switch len(m[1]) {
+		case 1:
+			color = bold + underline + magenta
+		case 2:
+			color = bold + cyan
+		}
+		r.write(color+inline(m[2])+reset, "", "")
+		return
+	}
+	if rulePattern.MatchString(line) {
+		io.WriteString(r.w, gray+strings.Repeat("─", r.width)+fgOff+"\n")
+		return
+	}
+	if m := bulletPattern.FindStringSubmatch(line); m != nil {
+		indent := m[1]
+		r.write(inline(m[2]), indent+yellow+bulletSymbol+fgOff+" ", indent+"  ")
+		return
+	}
+	if m := orderedPattern.FindStringSubmatch(line); m != nil {
+		indent := m[1]
+		r.write(inline(m[3]), indent+yellow+m[2]+fgOff+" ", indent+strings.Repeat(" ", len(m[2])+1))
+		return
+	}
+	if m := quotePattern.FindStringSubmatch(line); m != nil {
+		prefix := gray + "│ " + fgOff
+		r.write(italic+inline(m[1])+italicOff, prefix, prefix)
+		return
+	}
+
+	r.write(inline(line), "", "")
+}
+
+// write word-wraps text to the width, first prefixes the first line and rest the following ones
+func (r *Renderer) write(text, first, rest string) {
+	if strings.TrimSpace(text) == "" {
+		io.WriteString(r.w, "\n")
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(first)
+	col := visibleLen(first)
+	lineStart := true
+	for _, word := range strings.Fields(text) {
+		wordLen := visibleLen(word)
+		if !lineStart && col+1+wordLen > r.width {
+			b.WriteString("\n" + rest)
+			col = visibleLen(rest)
+			lineStart = true
+		}
+		if !lineStart {
+			b.WriteString(" ")
+			col++
+		}
+		b.WriteString(word)
+		col += wordLen
+		lineStart = false
+	}
+	b.WriteString("\n")
+	io.WriteString(r.w, b.String())
+}
+
+// inline styles code spans, bold, italic and links
+func inline(text string) string {
+	// links first, the escape sequences added below contain brackets
+	text = linkPattern.ReplaceAllString(text, "$1 ("+underline+blue+"$2"+fgOff+underlineOff+")")
+	text = inlineCodePattern.ReplaceAllString(text, green+"$1"+fgOff)
+	text = boldPattern.ReplaceAllString(text, bold+"$1$2"+boldOff)
+	text = italicPattern.ReplaceAllString(text, "$1$3"+italic+"$2$4"+italicOff)
+	return text
+}
+
+func visibleLen(text string) int {
+	return utf8.RuneCountInString(ansiPattern.ReplaceAllString(text, ""))
+}
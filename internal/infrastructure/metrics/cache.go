@@ -0,0 +1,23 @@
+package metrics
+
+import "github.com/aisalamdag23/promptme-cli/internal/infrastructure/caching"
+
+// instrumentedCache counts the hits and misses of a cache
+type instrumentedCache struct {
+	caching.Cache
+}
+
+// InstrumentCache wraps the cache to record its hits and misses
+func InstrumentCache(cache caching.Cache) caching.Cache {
+	return &instrumentedCache{Cache: cache}
+}
+
+func (c *instrumentedCache) Get(key string) (string, bool) {
+	value, exists := c.Cache.Get(key)
+	if exists {
+		CacheRequests.WithLabelValues("hit").Inc()
+	} else {
+		CacheRequests.WithLabelValues("miss").Inc()
+	}
+	return value, exists
+}
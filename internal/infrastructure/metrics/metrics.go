@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "promptme"
+
+var (
+	// Registry holds the metrics of the use cases, exposed by Handler or written by WriteTextfile
+	Registry = prometheus.NewRegistry()
+	factory  = promauto.With(Registry)
+
+	// runtime holds the go and process metrics, only served by Handler since the node exporter has its own
+	runtime = prometheus.NewRegistry()
+
+	// Requests generation requests by persona and outcome: ok, rejected or error
+	Requests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Generation requests by persona and outcome.",
+	}, []string{"persona", "outcome"})
+
+	// Errors failed requests by class: the rejecting stage, canceled, timeout or provider
+	Errors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Failed generation requests by class.",
+	}, []string{"class"})
+
+	// RequestDuration latency of the successful requests, from the prompt to the complete response
+	RequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of successful generation requests.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"persona", "cached"})
+
+	// TimeToFirstToken latency from the prompt to the first chunk of the response
+	TimeToFirstToken = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "time_to_first_token_seconds",
+		Help:      "Latency from the prompt to the first chunk of the response.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"persona"})
+
+	// CacheRequests response cache lookups by result: hit or miss
+	CacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Response cache lookups by result.",
+	}, []string{"result"})
+
+	// RateLimiterWait time spent waiting for the provider rate limiter
+	RateLimiterWait = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ratelimiter_wait_seconds",
+		Help:      "Time spent waiting for the provider rate limiter.",
+		Buckets:   []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 15, 30, 60},
+	})
+
+	// Tokens used by provider requests, by provider, model and type: prompt or completion
+	Tokens = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Tokens used by provider requests.",
+	}, []string{"provider", "model", "type"})
+)
+
+func init() {
+	runtime.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(prometheus.Gatherers{Registry, runtime}, promhttp.HandlerOpts{})
+}
+
+// WriteTextfile writes the metrics for the node exporter textfile collector, atomically
+func WriteTextfile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return prometheus.WriteToTextfile(path, Registry)
+}
@@ -2,7 +2,11 @@ package ratelimit
 
 import (
 	"context"
+	"time"
 
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/metrics"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/tracing"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 )
@@ -24,11 +28,25 @@ func NewRateLimiter(log *logrus.Entry, r rate.Limit, b int) *RateLimiter {
 // Do limiter wait
 func (rl *RateLimiter) Do(ctx context.Context) error {
 	// Wait for permission from the limiter before making the request
-	if err := rl.limiter.Wait(ctx); err != nil {
-		rl.log.Infoln("ratelimiter.do.wait")
+	_, span := tracing.Start(ctx, "ratelimiter.wait")
+	start := time.Now()
+	err := rl.limiter.Wait(ctx)
+	metrics.RateLimiterWait.Observe(time.Since(start).Seconds())
+	tracing.End(span, err)
+	if err != nil {
+		logger.ExtractOr(ctx, rl.log).Infoln("ratelimiter.do.wait")
 		return err
 	}
 
-	rl.log.Infoln("ratelimiter.do.continue")
+	logger.ExtractOr(ctx, rl.log).Infoln("ratelimiter.do.continue")
 	return nil
 }
+
+// Allow reports whether a request may happen now, without waiting
+func (rl *RateLimiter) Allow(ctx context.Context) bool {
+	if !rl.limiter.Allow() {
+		logger.ExtractOr(ctx, rl.log).Infoln("ratelimiter.allow.denied")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package sessions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	dirName       = "sessions"
+	fileExtension = ".json"
+)
+
+// FileStore stores each session as a JSON file in the data directory
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore init new session store
+func NewFileStore(cfg *config.Config) domain.SessionStore {
+	return &FileStore{
+		dir: filepath.Join(cfg.DataDirPath(), dirName),
+	}
+}
+
+// Save writes the session, replacing any previous version
+func (s *FileStore) Save(session *domain.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.MkdirAll(s.dir, 0700)
+	if err != nil {
+		return errors.Wrap(err, "cannot create sessions directory")
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal session")
+	}
+
+	// write then rename so an interrupted save never corrupts the session
+	tmp := s.path(session.ID) + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return errors.Wrap(err, "cannot write session")
+	}
+	return errors.Wrap(os.Rename(tmp, s.path(session.ID)), "cannot write session")
+}
+
+// Load returns the session with the given id or unique id prefix
+func (s *FileStore) Load(id string) (*domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.resolve(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.read(id)
+}
+
+// Latest returns the most recently updated session of the persona
+func (s *FileStore) Latest(persona string) (*domain.Session, error) {
+	list, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range list {
+		if session.Persona == persona {
+			return session, nil
+		}
+	}
+	return nil, domain.ErrSessionNotFound
+}
+
+// List returns all sessions, most recently updated first
+func (s *FileStore) List() ([]*domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*domain.Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := s.read(id)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, session)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].UpdatedAt.After(list[j].UpdatedAt)
+	})
+	return list, nil
+}
+
+// Delete removes the session with the given id or unique id prefix
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.resolve(id)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.Remove(s.path(id)), "cannot delete session")
+}
+
+// resolve expands an id prefix to the full session id
+func (s *FileStore) resolve(prefix string) (string, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	for _, id := range ids {
+		if id == prefix {
+			return id, nil
+		}
+		if strings.HasPrefix(id, prefix) {
+			matches = append(matches, id)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", domain.ErrSessionNotFound
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("session id %q is ambiguous, %d sessions match", prefix, len(matches))
+	}
+}
+
+func (s *FileStore) ids() ([]string, error) {
+	files, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read sessions directory")
+	}
+
+	var ids []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), fileExtension) {
+			ids = append(ids, strings.TrimSuffix(f.Name(), fileExtension))
+		}
+	}
+	return ids, nil
+}
+
+func (s *FileStore) read(id string) (*domain.Session, error) {
+	data, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, domain.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read session")
+	}
+
+	var session domain.Session
+	err = json.Unmarshal(data, &session)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot unmarshal session %s", id)
+	}
+	return &session, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+fileExtension)
+}
@@ -0,0 +1,148 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+
+	serviceName     = "promptme-cli"
+	tracerName      = "github.com/aisalamdag23/promptme-cli"
+	fileNameDefault = "traces.jsonl"
+)
+
+// apiKeyCfg holds the api key removed from the errors recorded on spans, provider errors may contain the request url.
+// It is read when recording since the secret references are resolved after Init
+var apiKeyCfg *config.Config
+
+// Init installs the tracer provider of the configured exporter, spans are dropped when tracing is off.
+// The returned func flushes the pending spans and must be called before exiting
+func Init(ctx context.Context, cfg *config.Config) (func(context.Context) error, error) {
+	apiKeyCfg = cfg
+	if cfg.Tracing.Exporter == "" || cfg.Tracing.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create trace resource")
+	}
+
+	ratio := cfg.Tracing.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg *config.Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Tracing.Exporter {
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Tracing.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint))
+		}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create otlp trace exporter")
+		}
+		return exporter, nil
+	case ExporterFile:
+		path := cfg.Tracing.File
+		if path == "" {
+			path = filepath.Join(cfg.DataDirPath(), fileNameDefault)
+		}
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create trace directory")
+		}
+		// closed by the exporter on shutdown
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot open trace file")
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, errors.Wrap(err, "cannot create file trace exporter")
+		}
+		return &fileExporter{SpanExporter: exporter, f: f}, nil
+	default:
+		return nil, errors.Errorf("unknown trace exporter %q", cfg.Tracing.Exporter)
+	}
+}
+
+// fileExporter closes the trace file once the spans are exported
+type fileExporter struct {
+	sdktrace.SpanExporter
+	f *os.File
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if closeErr := e.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Start starts a span of the application tracer, a child of the span in ctx if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span, marking it as failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		message := err.Error()
+		if apiKeyCfg != nil && apiKeyCfg.General.APIKey != "" {
+			message = strings.ReplaceAll(message, apiKeyCfg.General.APIKey, "***")
+		}
+		span.AddEvent("exception", trace.WithAttributes(
+			attribute.String("exception.type", fmt.Sprintf("%T", err)),
+			attribute.String("exception.message", message),
+		))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
@@ -0,0 +1,144 @@
+package billing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+
+	softThresholdDefault = 0.8
+)
+
+// budgetGuard refuses requests once a budget limit is reached
+type budgetGuard struct {
+	cfg    *config.Config
+	log    *logrus.Entry
+	ledger *Ledger
+	next   domain.LanguageModelService
+}
+
+// NewBudgetGuard wraps next with the budget check stage
+func NewBudgetGuard(cfg *config.Config, log *logrus.Entry, usageLedger *Ledger, next domain.LanguageModelService) domain.LanguageModelService {
+	if len(cfg.Budgets.Limits) == 0 {
+		return next
+	}
+
+	return &budgetGuard{
+		cfg:    cfg,
+		log:    log,
+		ledger: usageLedger,
+		next:   next,
+	}
+}
+
+// GenerateResponse checks every applicable budget before calling the next stage
+func (b *budgetGuard) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	warnings, err := b.check(ctx, userPrompt.Persona, userPrompt.Client, time.Now())
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	resp, err := b.next.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	resp.Warnings = append(resp.Warnings, warnings...)
+	return resp, nil
+}
+
+// check returns the soft threshold warnings, or a rejection when a hard limit is reached
+func (b *budgetGuard) check(ctx context.Context, persona, client string, now time.Time) ([]string, error) {
+	var warnings []string
+	for _, limit := range b.cfg.Budgets.Limits {
+		if !limitApplies(limit, b.cfg.LLM.Provider, persona, client) {
+			continue
+		}
+
+		tokens, cost, err := b.ledger.Spent(limit, now)
+		if err != nil {
+			return nil, err
+		}
+		usage, ratio := b.usage(limit, tokens, cost)
+		log := logger.ExtractOr(ctx, b.log).WithFields(logrus.Fields{
+			"budget.period":   limit.Period,
+			"budget.provider": limit.Provider,
+			"budget.persona":  limit.Persona,
+			"budget.used":     ratio,
+		})
+
+		switch {
+		case ratio >= 1:
+			log.Warnln("budget.limit.exceeded")
+			return nil, &domain.RejectionError{
+				Stage:   domain.REJECTION_STAGE_BUDGET,
+				Reason:  "hard_limit",
+				Message: fmt.Sprintf(domain.BUDGET_EXCEEDED_MESSAGE, usage),
+			}
+		case ratio >= b.softThreshold():
+			log.Warnln("budget.limit.soft")
+			warnings = append(warnings, fmt.Sprintf(domain.BUDGET_WARNING_MESSAGE, usage))
+		}
+	}
+
+	return warnings, nil
+}
+
+// usage describes the most used dimension of the limit and returns its ratio
+func (b *budgetGuard) usage(limit config.BudgetLimit, tokens int, cost float64) (string, float64) {
+	var description string
+	var ratio float64
+	if limit.MaxTokens > 0 {
+		ratio = float64(tokens) / float64(limit.MaxTokens)
+		description = fmt.Sprintf("%d of %d tokens", tokens, limit.MaxTokens)
+	}
+	if limit.MaxCost > 0 && cost/limit.MaxCost >= ratio {
+		ratio = cost / limit.MaxCost
+		description = fmt.Sprintf("%.4f of %.4f %s", cost, limit.MaxCost, b.cfg.CurrencyCode())
+	}
+	return fmt.Sprintf("%s used by the %s %s budget", description, limit.Period, limitScope(limit)), ratio
+}
+
+func (b *budgetGuard) softThreshold() float64 {
+	if b.cfg.Budgets.SoftThreshold > 0 {
+		return b.cfg.Budgets.SoftThreshold
+	}
+	return softThresholdDefault
+}
+
+func limitApplies(limit config.BudgetLimit, provider, persona, client string) bool {
+	return (limit.Provider == "" || limit.Provider == provider) &&
+		(limit.Persona == "" || limit.Persona == persona) &&
+		(limit.Client == "" || limit.Client == client)
+}
+
+func limitScope(limit config.BudgetLimit) string {
+	var scopes []string
+	for _, scope := range []string{limit.Provider, limit.Persona, limit.Client} {
+		if scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return "global"
+	}
+	return strings.Join(scopes, "/")
+}
+
+func periodStart(period string, now time.Time) time.Time {
+	year, month, day := now.Date()
+	if period == PeriodMonthly {
+		return time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+}
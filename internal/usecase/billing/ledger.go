@@ -0,0 +1,112 @@
+package billing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/ledger"
+)
+
+type (
+	// Ledger appends to the usage ledger and keeps the running spend of every budget limit in memory,
+	// seeded from the ledger on first use, so that budget checks do not read the ledger file
+	Ledger struct {
+		ledger.Ledger
+		cfg *config.Config
+
+		// mu guards the totals, and the appends so that an entry is never counted by both the seed and Append
+		mu     sync.Mutex
+		seeded bool
+		totals map[config.BudgetLimit]*spend
+	}
+
+	// spend of a limit since the start of its current period
+	spend struct {
+		start  time.Time
+		tokens int
+		cost   float64
+	}
+)
+
+// NewLedger wraps the usage ledger with the running spend of the configured budget limits
+func NewLedger(cfg *config.Config, next ledger.Ledger) *Ledger {
+	return &Ledger{
+		Ledger: next,
+		cfg:    cfg,
+		totals: make(map[config.BudgetLimit]*spend),
+	}
+}
+
+// Append appends the entry to the ledger and adds it to the spend of the limits it applies to
+func (l *Ledger) Append(entry ledger.Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.Ledger.Append(entry)
+	if err != nil {
+		return err
+	}
+	// until seeded, the seed reads the entry from the ledger
+	if l.seeded {
+		l.add(entry)
+	}
+	return nil
+}
+
+// Spent returns the tokens and cost used by the limit in its current period
+func (l *Ledger) Spent(limit config.BudgetLimit, now time.Time) (int, float64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.seeded {
+		if err := l.seed(now); err != nil {
+			return 0, 0, err
+		}
+	}
+	s := l.current(limit, now)
+	return s.tokens, s.cost, nil
+}
+
+// seed sums the entries of the current month, which covers the current day
+func (l *Ledger) seed(now time.Time) error {
+	entries, err := l.Ledger.Entries(periodStart(PeriodMonthly, now))
+	if err != nil {
+		return err
+	}
+
+	for _, limit := range l.cfg.Budgets.Limits {
+		l.totals[limit] = &spend{start: periodStart(limit.Period, now)}
+	}
+	for _, entry := range entries {
+		l.add(entry)
+	}
+	l.seeded = true
+	return nil
+}
+
+// add adds the entry to the spend of the limits it applies to, within their current period
+func (l *Ledger) add(entry ledger.Entry) {
+	for limit := range l.totals {
+		if !limitApplies(limit, entry.Provider, entry.Persona, entry.Client) {
+			continue
+		}
+		s := l.current(limit, entry.Time)
+		if entry.Time.Before(s.start) {
+			continue
+		}
+		s.tokens += entry.TotalTokens
+		s.cost += entry.Cost
+	}
+}
+
+// current returns the spend of the limit, starting over once a new period started at now
+func (l *Ledger) current(limit config.BudgetLimit, now time.Time) *spend {
+	start := periodStart(limit.Period, now)
+	s, ok := l.totals[limit]
+	if !ok || s.start.Before(start) {
+		s = &spend{start: start}
+		l.totals[limit] = s
+	}
+	return s
+}
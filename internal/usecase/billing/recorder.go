@@ -0,0 +1,72 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/ledger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// recorder estimates the cost of every provider request and appends it to the ledger
+type recorder struct {
+	cfg    *config.Config
+	log    *logrus.Entry
+	ledger ledger.Ledger
+	next   domain.LanguageModelService
+}
+
+// NewRecorder wraps a provider with the usage recording stage
+func NewRecorder(cfg *config.Config, log *logrus.Entry, usageLedger ledger.Ledger, next domain.LanguageModelService) domain.LanguageModelService {
+	return &recorder{
+		cfg:    cfg,
+		log:    log,
+		ledger: usageLedger,
+		next:   next,
+	}
+}
+
+// GenerateResponse calls the provider and records the usage of the response
+func (r *recorder) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	resp, err := r.next.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	resp.Cost = Cost(r.cfg, resp.Model, resp.Usage)
+	metrics.Tokens.WithLabelValues(resp.Provider, resp.Model, "prompt").Add(float64(resp.Usage.PromptTokens))
+	metrics.Tokens.WithLabelValues(resp.Provider, resp.Model, "completion").Add(float64(resp.Usage.CompletionTokens))
+
+	err = r.ledger.Append(ledger.Entry{
+		Time:             time.Now(),
+		RequestID:        userPrompt.RequestID,
+		Persona:          userPrompt.Persona,
+		Client:           userPrompt.Client,
+		Provider:         resp.Provider,
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+		Cost:             resp.Cost,
+		Currency:         r.cfg.CurrencyCode(),
+	})
+	if err != nil {
+		// losing a ledger entry must not lose the answer
+		logger.ExtractOr(ctx, r.log).Errorln("ledger.append.failed:", err)
+	}
+
+	return resp, nil
+}
+
+// Cost estimates the cost of the usage with the configured pricing, 0 for unpriced models
+func Cost(cfg *config.Config, model string, usage domain.Usage) float64 {
+	price, ok := cfg.LLM.Pricing[model]
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.InputPerMillion + float64(usage.CompletionTokens)*price.OutputPerMillion) / 1e6
+}
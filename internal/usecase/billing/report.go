@@ -0,0 +1,70 @@
+package billing
+
+import (
+	"sort"
+
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/ledger"
+)
+
+const (
+	GroupByDay     = "day"
+	GroupByModel   = "model"
+	GroupByPersona = "persona"
+	GroupByClient  = "client"
+
+	dayFormat = "2006-01-02"
+)
+
+// Total aggregated usage of a group of ledger entries
+type Total struct {
+	Key              string  `json:"key"`
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// Summarize aggregates entries by day, model, persona or client, sorted by key
+func Summarize(entries []ledger.Entry, groupBy string) []Total {
+	totals := make(map[string]*Total)
+	for _, entry := range entries {
+		key := groupKey(entry, groupBy)
+		total, ok := totals[key]
+		if !ok {
+			total = &Total{Key: key}
+			totals[key] = total
+		}
+		total.Requests++
+		total.PromptTokens += entry.PromptTokens
+		total.CompletionTokens += entry.CompletionTokens
+		total.Cost += entry.Cost
+	}
+
+	result := make([]Total, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func groupKey(entry ledger.Entry, groupBy string) string {
+	switch groupBy {
+	case GroupByModel:
+		return entry.Provider + "/" + entry.Model
+	case GroupByPersona:
+		if entry.Persona == "" {
+			return "-"
+		}
+		return entry.Persona
+	case GroupByClient:
+		if entry.Client == "" {
+			return "-"
+		}
+		return entry.Client
+	default:
+		return entry.Time.Local().Format(dayFormat)
+	}
+}
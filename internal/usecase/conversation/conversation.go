@@ -0,0 +1,200 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/tracing"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/history"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type (
+	// Service answers prompts within saved sessions, for the REPL and the servers
+	Service struct {
+		cfg            *config.Config
+		log            *logrus.Entry
+		generators     Generators
+		historyManager *history.Manager
+		store          domain.SessionStore
+
+		// locks serializes the prompts of a session so no turn is lost, a lock is kept
+		// while prompts hold or wait for it, mu guards the map
+		mu    sync.Mutex
+		locks map[string]*sessionLock
+	}
+
+	// sessionLock the lock of a session and the number of prompts holding or waiting for it
+	sessionLock struct {
+		mu   sync.Mutex
+		refs int
+	}
+
+	// Generators returns the language model service of a persona
+	Generators interface {
+		Get(persona string) (domain.LanguageModelService, error)
+	}
+)
+
+func NewService(cfg *config.Config, log *logrus.Entry, generators Generators, historyManager *history.Manager, store domain.SessionStore) *Service {
+	return &Service{
+		cfg:            cfg,
+		log:            log,
+		generators:     generators,
+		historyManager: historyManager,
+		store:          store,
+		locks:          make(map[string]*sessionLock),
+	}
+}
+
+// NewSession starts an unsaved session of the client with the persona
+func (s *Service) NewSession(persona, client string) *domain.Session {
+	now := time.Now()
+	return &domain.Session{
+		ID:        uuid.NewString(),
+		Persona:   persona,
+		Provider:  s.cfg.LLM.Provider,
+		Model:     s.cfg.LLM.Gemini.Model,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Server:    true,
+		Client:    client,
+	}
+}
+
+// Load returns the saved session of the client with exactly the given id,
+// the sessions of the CLI and of other clients are not found
+func (s *Service) Load(id, client string) (*domain.Session, error) {
+	// a full id never matches another session as a prefix
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, domain.ErrSessionNotFound
+	}
+
+	session, err := s.store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if session.ID != id || !session.Server || session.Client != client {
+		return nil, domain.ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// Continue loads the session of the prompt's client and answers the prompt within it, see Ask
+func (s *Service) Continue(ctx context.Context, id string, userPrompt domain.UserPrompt) (*domain.Session, domain.Response, error) {
+	session, err := s.Load(id, userPrompt.Client)
+	if err != nil {
+		return nil, domain.Response{}, err
+	}
+
+	unlock := s.lock(session.ID)
+	defer unlock()
+
+	// another prompt may have been answered while waiting for the lock
+	session, err = s.Load(session.ID, userPrompt.Client)
+	if err != nil {
+		return nil, domain.Response{}, err
+	}
+
+	resp, err := s.ask(ctx, session, userPrompt)
+	return session, resp, err
+}
+
+// Ask sends the prompt with the session history, compacted if needed, then adds the turn to the session and saves it.
+// The persona, summary and history of the prompt are taken from the session, which is reloaded first
+// so that the turns saved by other connections meanwhile are kept.
+func (s *Service) Ask(ctx context.Context, session *domain.Session, userPrompt domain.UserPrompt) (domain.Response, error) {
+	unlock := s.lock(session.ID)
+	defer unlock()
+
+	saved, err := s.store.Load(session.ID)
+	switch {
+	case err == nil:
+		*session = *saved
+	case errors.Is(err, domain.ErrSessionNotFound):
+		// not saved until its first prompt is answered
+	default:
+		return domain.Response{}, err
+	}
+
+	return s.ask(ctx, session, userPrompt)
+}
+
+func (s *Service) ask(ctx context.Context, session *domain.Session, userPrompt domain.UserPrompt) (_ domain.Response, err error) {
+	ctx, span := tracing.Start(ctx, "conversation.ask",
+		attribute.String("promptme.request_id", userPrompt.RequestID),
+		attribute.String("promptme.session_id", session.ID),
+	)
+	defer func() { tracing.End(span, err) }()
+	log := logger.ExtractOr(ctx, s.log)
+
+	srv, err := s.generators.Get(session.Persona)
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	// keep the history within the context window before sending it along
+	compacted, err := s.historyManager.Compact(ctx, session, userPrompt.RequestID)
+	if err != nil {
+		log.Warnln("historymanager.compact.failed:", err)
+	}
+	if compacted {
+		s.save(log, session)
+	}
+
+	userPrompt.Persona = session.Persona
+	userPrompt.Summary = session.Summary
+	userPrompt.History = session.ActiveTurns()
+
+	startTime := time.Now()
+	resp, err := srv.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		return domain.Response{}, err
+	}
+	resp.StartTime = startTime
+	resp.EndTime = time.Now()
+	resp.ResponseTime = resp.EndTime.Sub(resp.StartTime)
+	log.Infoln("response.time:", resp.ResponseTime)
+
+	session.AddTurn(userPrompt, resp)
+	s.save(log, session)
+	return resp, nil
+}
+
+func (s *Service) save(log *logrus.Entry, session *domain.Session) {
+	// losing the session must not lose the answer
+	if err := s.store.Save(session); err != nil {
+		log.Errorln("sessionstore.save.failed:", err)
+	}
+}
+
+// lock locks the session until the returned func is called, the lock is dropped once no prompt needs it
+func (s *Service) lock(id string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &sessionLock{}
+		s.locks[id] = l
+	}
+	l.refs++
+	s.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, id)
+		}
+	}
+}
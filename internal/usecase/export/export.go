@@ -0,0 +1,146 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+)
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+
+	timeFormat = "2006-01-02 15:04:05"
+)
+
+// Formats supported by Render
+var Formats = []string{FormatMarkdown, FormatHTML, FormatJSON}
+
+// FormatFromPath infers the format from a file extension, empty if unknown
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm":
+		return FormatHTML
+	case ".json":
+		return FormatJSON
+	default:
+		return ""
+	}
+}
+
+// Render writes the session in the given format
+func Render(w io.Writer, session *domain.Session, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(w, session)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, session)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(session)
+	default:
+		return fmt.Errorf("unsupported export format %q, expected one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+func renderMarkdown(w io.Writer, session *domain.Session) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", sessionTitle(session))
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", session.ID)
+	fmt.Fprintf(&b, "- **Persona:** %s\n", session.Persona)
+	fmt.Fprintf(&b, "- **Model:** %s/%s\n", session.Provider, session.Model)
+	fmt.Fprintf(&b, "- **Created:** %s\n", session.CreatedAt.Local().Format(timeFormat))
+	fmt.Fprintf(&b, "- **Updated:** %s\n", session.UpdatedAt.Local().Format(timeFormat))
+
+	for i, turn := range session.Turns {
+		fmt.Fprintf(&b, "\n---\n\n## Turn %d\n\n", i+1)
+		fmt.Fprintf(&b, "_%s · %s_\n\n", turn.StartTime.Local().Format(timeFormat), turnMeta(turn))
+		fmt.Fprintf(&b, "**You:**\n\n%s\n\n", quote(turn.Prompt))
+		fmt.Fprintf(&b, "**Coach:**\n\n%s\n", turn.Response)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// turnMeta one line description of the timing and model metadata of a turn
+func turnMeta(turn domain.Turn) string {
+	parts := []string{"response time " + turn.ResponseTime.String()}
+	if turn.Cached {
+		parts = append(parts, "cached")
+	} else {
+		parts = append(parts, fmt.Sprintf("%d in / %d out tokens", turn.Usage.PromptTokens, turn.Usage.CompletionTokens))
+	}
+	if turn.Model != "" {
+		parts = append(parts, turn.Model)
+	}
+	if turn.FinishReason != "" {
+		parts = append(parts, "finish "+turn.FinishReason)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func sessionTitle(session *domain.Session) string {
+	if session.Name != "" {
+		return session.Name
+	}
+	return "Session " + session.ID
+}
+
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
+
+var htmlTemplate = template.Must(template.New("session").Funcs(template.FuncMap{
+	"title": sessionTitle,
+	"meta":  turnMeta,
+	"time": func(session *domain.Session) string {
+		return session.CreatedAt.Local().Format(timeFormat)
+	},
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ title . }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 50rem; margin: 2rem auto; padding: 0 1rem; color: #222; line-height: 1.5; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1.5rem; }
+header dl { display: grid; grid-template-columns: max-content auto; gap: .25rem 1rem; color: #555; }
+header dt { font-weight: 600; }
+.turn { margin-bottom: 2rem; }
+.meta { color: #777; font-size: .85rem; }
+.prompt, .response { padding: .75rem 1rem; border-radius: .5rem; white-space: pre-wrap; }
+.prompt { background: #eef4ff; }
+.response { background: #f6f6f6; margin-top: .5rem; }
+.label { font-weight: 600; display: block; margin-bottom: .25rem; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{ title . }}</h1>
+<dl>
+<dt>Session</dt><dd>{{ .ID }}</dd>
+<dt>Persona</dt><dd>{{ .Persona }}</dd>
+<dt>Model</dt><dd>{{ .Provider }}/{{ .Model }}</dd>
+<dt>Created</dt><dd>{{ time . }}</dd>
+</dl>
+</header>
+{{ range $i, $turn := .Turns }}<section class="turn">
+<h2>Turn {{ inc $i }}</h2>
+<p class="meta">{{ $turn.StartTime.Local.Format "2006-01-02 15:04:05" }} · {{ meta $turn }}</p>
+<div class="prompt"><span class="label">You</span>{{ $turn.Prompt }}</div>
+<div class="response"><span class="label">Coach</span>{{ $turn.Response }}</div>
+</section>
+{{ end }}</body>
+</html>
+`))
@@ -0,0 +1,176 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	moderationStage = "moderation"
+
+	ModerationActionRedact = "redact"
+	ModerationActionRetry  = "retry"
+	ModerationActionRefuse = "refuse"
+)
+
+// moderation validates responses after generation against the topic and banned content rules
+type moderation struct {
+	cfg      *config.Config
+	log      *logrus.Entry
+	next     domain.LanguageModelService
+	judge    domain.LanguageModelService
+	banned   []*regexp.Regexp
+	keywords [][]string
+}
+
+// NewModeration wraps next with the response validation stage,
+// judge is optional and only asked when the topic check is on
+func NewModeration(cfg *config.Config, log *logrus.Entry, next, judge domain.LanguageModelService) (domain.LanguageModelService, error) {
+	if !cfg.Guardrails.Moderation.Enabled {
+		return next, nil
+	}
+
+	banned, err := compilePatterns(cfg.Guardrails.Moderation.BannedPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid moderation banned pattern: %w", err)
+	}
+
+	return &moderation{
+		cfg:      cfg,
+		log:      log,
+		next:     next,
+		judge:    judge,
+		banned:   banned,
+		keywords: keywordStems(cfg.LLM.Keywords),
+	}, nil
+}
+
+// GenerateResponse generates a response and applies the configured action when it fails validation.
+// A streamed response is buffered and only emitted once it passed validation
+func (m *moderation) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	stream := userPrompt.Stream
+	userPrompt.Stream = nil
+
+	resp, err := m.generate(ctx, userPrompt)
+	if err == nil && stream != nil {
+		stream(resp.Text)
+	}
+	return resp, err
+}
+
+func (m *moderation) generate(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	resp, err := m.next.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		reason, redactable := m.validate(ctx, userPrompt, resp.Text)
+		if reason == "" {
+			logger.ExtractOr(ctx, m.log).Infoln("moderation.response.accepted")
+			return resp, nil
+		}
+
+		log := logger.ExtractOr(ctx, m.log).WithField("reason", reason)
+		switch {
+		case m.action() == ModerationActionRedact && redactable:
+			log.Infoln("moderation.response.redacted")
+			resp.Text = m.redact(resp.Text)
+			return resp, nil
+
+		case m.action() == ModerationActionRetry && attempt < m.cfg.Guardrails.Moderation.MaxRetries:
+			log.Infoln("moderation.response.retried")
+			strict := userPrompt
+			strict.Instruction = strings.TrimSpace(userPrompt.Instruction + "\n\n" + m.strictInstruction())
+			retried, err := m.next.GenerateResponse(ctx, strict)
+			if err != nil {
+				return domain.Response{}, err
+			}
+			// the rejected attempts were billed too
+			retried.Usage = resp.Usage.Add(retried.Usage)
+			retried.Cost += resp.Cost
+			resp = retried
+
+		default:
+			log.Infoln("moderation.response.refused")
+			return domain.Response{}, &domain.RejectionError{
+				Stage:   moderationStage,
+				Reason:  reason,
+				Message: m.refusalMessage(),
+			}
+		}
+	}
+}
+
+// validate returns why the response failed, empty if it passed,
+// and whether the failure can be fixed by redacting
+func (m *moderation) validate(ctx context.Context, userPrompt domain.UserPrompt, resp string) (string, bool) {
+	for _, re := range m.banned {
+		if re.MatchString(resp) {
+			return "banned_pattern:" + re.String(), true
+		}
+	}
+
+	if !m.cfg.Guardrails.Moderation.CheckTopic || len(m.keywords) == 0 {
+		return "", false
+	}
+
+	respStems := stems(resp)
+	for _, keyword := range m.keywords {
+		if containsAll(respStems, keyword) {
+			return "", false
+		}
+	}
+
+	if m.judge == nil {
+		return "off_topic", false
+	}
+
+	ok, err := askYesNo(ctx, m.judge, userPrompt, fmt.Sprintf(domain.RESPONSE_JUDGE_PROMPT, m.cfg.LLM.Keywords, resp))
+	if err != nil {
+		// fail open, the keyword check alone is too strict to refuse on
+		logger.ExtractOr(ctx, m.log).Warnln("moderation.judge.failed:", err)
+		return "", false
+	}
+	if !ok {
+		return "judge:off_topic", false
+	}
+
+	return "", false
+}
+
+func (m *moderation) redact(resp string) string {
+	for _, re := range m.banned {
+		resp = re.ReplaceAllString(resp, domain.REDACTED_TEXT)
+	}
+	return resp
+}
+
+func (m *moderation) action() string {
+	if m.cfg.Guardrails.Moderation.Action == "" {
+		return ModerationActionRefuse
+	}
+	return m.cfg.Guardrails.Moderation.Action
+}
+
+func (m *moderation) strictInstruction() string {
+	topic := m.cfg.LLM.Keywords
+	if topic == "" {
+		topic = "the question"
+	}
+	return fmt.Sprintf(domain.STRICT_INSTRUCTION, topic)
+}
+
+func (m *moderation) refusalMessage() string {
+	if m.cfg.Guardrails.Moderation.RefusalMessage != "" {
+		return m.cfg.Guardrails.Moderation.RefusalMessage
+	}
+	return domain.REFUSAL_MESSAGE
+}
@@ -0,0 +1,244 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minPhoneDigits avoids treating years ranges or small numbers as phone numbers
+	minPhoneDigits = 9
+	// maxPlaceholderLen longest tail held back while streaming, placeholders are short
+	maxPlaceholderLen = 64
+)
+
+type detector struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// builtinDetectors in the order they are applied, more specific patterns first
+var builtinDetectors = []detector{
+	{name: "email", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{name: "url", re: regexp.MustCompile(`\b(?:https?://|www\.)[^\s<>"']*[^\s<>"'.,;:!?)]`)},
+	{name: "national_id", re: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b|\b[A-CEGHJ-PR-TW-Z]{2}\s?\d{2}\s?\d{2}\s?\d{2}\s?[A-D]\b`)},
+	{name: "phone", re: regexp.MustCompile(`\+?(?:\(?\d[\d().\s-]{6,}\d)`), valid: func(match string) bool {
+		digits := 0
+		for _, r := range match {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		return digits >= minPhoneDigits
+	}},
+}
+
+// Redactor replaces PII with numbered placeholders, e.g. [EMAIL_1]
+type Redactor struct {
+	detectors []detector
+}
+
+// NewRedactor creates a redactor with the configured detectors,
+// a disabled redactor leaves text untouched
+func NewRedactor(cfg *config.Config) (*Redactor, error) {
+	if !cfg.Guardrails.Redaction.Enabled {
+		return &Redactor{}, nil
+	}
+
+	r := &Redactor{}
+	for _, d := range builtinDetectors {
+		if len(cfg.Guardrails.Redaction.Detectors) == 0 || slices.Contains(cfg.Guardrails.Redaction.Detectors, d.name) {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+
+	names := make([]string, 0, len(cfg.Guardrails.Redaction.CustomPatterns))
+	for name := range cfg.Guardrails.Redaction.CustomPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		re, err := regexp.Compile(cfg.Guardrails.Redaction.CustomPatterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %s: %w", name, err)
+		}
+		r.detectors = append(r.detectors, detector{name: name, re: re})
+	}
+
+	return r, nil
+}
+
+// Redact returns the masked text and the placeholder to original value mapping
+func (r *Redactor) Redact(text string) (string, map[string]string) {
+	mapping := make(map[string]string)
+	return r.RedactWith(text, mapping), mapping
+}
+
+// RedactWith masks text reusing and extending mapping, so the same value
+// gets the same placeholder across several texts of a conversation
+func (r *Redactor) RedactWith(text string, mapping map[string]string) string {
+	if r == nil {
+		return text
+	}
+
+	placeholders := make(map[string]string, len(mapping))
+	counts := make(map[string]int)
+	for placeholder, value := range mapping {
+		placeholders[value] = placeholder
+		counts[placeholder[1:strings.LastIndex(placeholder, "_")]]++
+	}
+
+	for _, d := range r.detectors {
+		prefix := strings.ToUpper(d.name)
+		text = d.re.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			if placeholder, ok := placeholders[match]; ok {
+				return placeholder
+			}
+			counts[prefix]++
+			placeholder := fmt.Sprintf("[%s_%d]", prefix, counts[prefix])
+			placeholders[match] = placeholder
+			mapping[placeholder] = match
+			return placeholder
+		})
+	}
+
+	return text
+}
+
+// Mask returns the masked text, used for log fields
+func (r *Redactor) Mask(text string) string {
+	masked, _ := r.Redact(text)
+	return masked
+}
+
+// Restore puts the original values back in place of their placeholders
+func (r *Redactor) Restore(text string, mapping map[string]string) string {
+	for placeholder, value := range mapping {
+		text = strings.ReplaceAll(text, placeholder, value)
+	}
+	return text
+}
+
+// redactingCounter masks PII before texts are sent to a provider's token counter
+type redactingCounter struct {
+	redactor *Redactor
+	next     domain.TokenCounter
+}
+
+// NewRedactingCounter wraps a token counter so it never receives PII
+func NewRedactingCounter(cfg *config.Config, next domain.TokenCounter, redactor *Redactor) domain.TokenCounter {
+	if !cfg.Guardrails.Redaction.Enabled {
+		return next
+	}
+	return &redactingCounter{redactor: redactor, next: next}
+}
+
+// CountTokens counts the tokens of the masked texts
+func (c *redactingCounter) CountTokens(ctx context.Context, texts []string) (int, error) {
+	masked := make([]string, len(texts))
+	for i, text := range texts {
+		masked[i] = c.redactor.Mask(text)
+	}
+	return c.next.CountTokens(ctx, masked)
+}
+
+// redaction masks PII in prompts before they reach any other stage
+type redaction struct {
+	cfg      *config.Config
+	log      *logrus.Entry
+	next     domain.LanguageModelService
+	redactor *Redactor
+}
+
+// NewRedaction wraps next with the PII redaction stage
+func NewRedaction(cfg *config.Config, log *logrus.Entry, next domain.LanguageModelService, redactor *Redactor) domain.LanguageModelService {
+	if !cfg.Guardrails.Redaction.Enabled {
+		return next
+	}
+
+	return &redaction{
+		cfg:      cfg,
+		log:      log,
+		next:     next,
+		redactor: redactor,
+	}
+}
+
+// GenerateResponse sends the masked prompt and optionally restores the original values in the response
+func (r *redaction) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	mapping := make(map[string]string)
+	history := make([]domain.Turn, len(userPrompt.History))
+	for i, turn := range userPrompt.History {
+		turn.Prompt = r.redactor.RedactWith(turn.Prompt, mapping)
+		turn.Response = r.redactor.RedactWith(turn.Response, mapping)
+		history[i] = turn
+	}
+	userPrompt.History = history
+	userPrompt.Summary = r.redactor.RedactWith(userPrompt.Summary, mapping)
+	userPrompt.Text = r.redactor.RedactWith(userPrompt.Text, mapping)
+	if len(mapping) > 0 {
+		logger.ExtractOr(ctx, r.log).WithField("placeholders", len(mapping)).Infoln("redaction.prompt.masked")
+	}
+
+	var stream *restoringStream
+	if r.cfg.Guardrails.Redaction.RestoreResponse && userPrompt.Stream != nil && len(mapping) > 0 {
+		stream = &restoringStream{redactor: r.redactor, mapping: mapping, next: userPrompt.Stream}
+		userPrompt.Stream = stream.write
+	}
+
+	resp, err := r.next.GenerateResponse(ctx, userPrompt)
+	if stream != nil {
+		stream.flush()
+	}
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	if r.cfg.Guardrails.Redaction.RestoreResponse {
+		resp.Text = r.redactor.Restore(resp.Text, mapping)
+	}
+
+	return resp, nil
+}
+
+// restoringStream restores placeholders in streamed chunks,
+// holding back a tail that may be the start of a placeholder split across chunks
+type restoringStream struct {
+	redactor *Redactor
+	mapping  map[string]string
+	next     func(chunk string)
+	pending  string
+}
+
+func (s *restoringStream) write(chunk string) {
+	text := s.pending + chunk
+	cut := len(text)
+	if i := strings.LastIndex(text, "["); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i <= maxPlaceholderLen {
+		cut = i
+	}
+	s.pending = text[cut:]
+	if cut > 0 {
+		s.next(s.redactor.Restore(text[:cut], s.mapping))
+	}
+}
+
+func (s *restoringStream) flush() {
+	if s.pending != "" {
+		s.next(s.pending)
+		s.pending = ""
+	}
+}
@@ -0,0 +1,44 @@
+package guardrails
+
+import (
+	"strings"
+	"unicode"
+)
+
+// suffixes stripped by stem, longest first
+var suffixes = []string{
+	"ations", "ation", "ments", "ment", "ional", "ings", "ing",
+	"ness", "ies", "ers", "er", "ed", "es", "ly", "al", "s",
+}
+
+// tokenize splits text into lowercase words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// stem is a light suffix stripping stemmer, good enough to match
+// "careers", "interviewing" or "employment" against their keywords
+func stem(word string) string {
+	for _, suffix := range suffixes {
+		if len(word)-len(suffix) < 3 || !strings.HasSuffix(word, suffix) {
+			continue
+		}
+		word = strings.TrimSuffix(word, suffix)
+		if suffix == "ies" {
+			word += "y"
+		}
+		break
+	}
+	return word
+}
+
+// stems returns the set of stemmed words in text
+func stems(text string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range tokenize(text) {
+		set[stem(word)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,166 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const topicGuardStage = "topic_guard"
+
+// topicGuard rejects off-topic prompts locally before they reach the language model
+type topicGuard struct {
+	cfg        *config.Config
+	log        *logrus.Entry
+	next       domain.LanguageModelService
+	classifier domain.LanguageModelService
+	allow      []*regexp.Regexp
+	deny       []*regexp.Regexp
+	keywords   [][]string
+}
+
+// NewTopicGuard wraps next with the pre-flight topic classifier,
+// classifier is optional and only asked when no keyword or pattern matches
+func NewTopicGuard(cfg *config.Config, log *logrus.Entry, next, classifier domain.LanguageModelService) (domain.LanguageModelService, error) {
+	if !cfg.Guardrails.Topic.Enabled || cfg.LLM.Keywords == "" {
+		return next, nil
+	}
+
+	allow, err := compilePatterns(cfg.Guardrails.Topic.AllowPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic allow pattern: %w", err)
+	}
+	deny, err := compilePatterns(cfg.Guardrails.Topic.DenyPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic deny pattern: %w", err)
+	}
+
+	return &topicGuard{
+		cfg:        cfg,
+		log:        log,
+		next:       next,
+		classifier: classifier,
+		allow:      allow,
+		deny:       deny,
+		keywords:   keywordStems(cfg.LLM.Keywords),
+	}, nil
+}
+
+// GenerateResponse only forwards the prompt to the language model when it is on topic
+func (g *topicGuard) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	onTopic, reason := g.classify(ctx, userPrompt)
+	if !onTopic {
+		logger.ExtractOr(ctx, g.log).WithField("reason", reason).Infoln("topicguard.prompt.rejected")
+		return domain.Response{}, &domain.RejectionError{
+			Stage:   topicGuardStage,
+			Reason:  reason,
+			Message: g.rejectionMessage(),
+		}
+	}
+
+	logger.ExtractOr(ctx, g.log).WithField("reason", reason).Infoln("topicguard.prompt.accepted")
+	return g.next.GenerateResponse(ctx, userPrompt)
+}
+
+// classify returns whether the prompt is on topic, and why
+func (g *topicGuard) classify(ctx context.Context, userPrompt domain.UserPrompt) (bool, string) {
+	text := userPrompt.Text
+	for _, re := range g.deny {
+		if re.MatchString(text) {
+			return false, "deny_pattern:" + re.String()
+		}
+	}
+	for _, re := range g.allow {
+		if re.MatchString(text) {
+			return true, "allow_pattern:" + re.String()
+		}
+	}
+
+	// a follow-up such as "tell me more" is on topic if the previous prompt was
+	if len(userPrompt.History) > 0 {
+		text = userPrompt.History[len(userPrompt.History)-1].Prompt + "\n" + text
+	}
+
+	promptStems := stems(text)
+	for _, keyword := range g.keywords {
+		if containsAll(promptStems, keyword) {
+			return true, "keyword:" + strings.Join(keyword, " ")
+		}
+	}
+
+	if g.classifier == nil {
+		return false, "no_keyword_match"
+	}
+
+	ok, err := askYesNo(ctx, g.classifier, userPrompt, fmt.Sprintf(domain.TOPIC_CLASSIFIER_PROMPT, g.cfg.LLM.Keywords, text))
+	if err != nil {
+		// fail open, the system instruction still restricts the topic
+		logger.ExtractOr(ctx, g.log).Warnln("topicguard.classifier.failed:", err)
+		return true, "classifier_error"
+	}
+
+	return ok, "classifier"
+}
+
+func (g *topicGuard) rejectionMessage() string {
+	if g.cfg.Guardrails.Topic.RejectionMessage != "" {
+		return g.cfg.Guardrails.Topic.RejectionMessage
+	}
+	return fmt.Sprintf(domain.OFF_TOPIC_MESSAGE, strings.ReplaceAll(g.cfg.LLM.Keywords, ",", ", "))
+}
+
+// askYesNo sends a yes/no question about userPrompt to a utility model
+func askYesNo(ctx context.Context, model domain.LanguageModelService, userPrompt domain.UserPrompt, question string) (bool, error) {
+	answer, err := model.GenerateResponse(ctx, domain.UserPrompt{
+		Text:      question,
+		RequestID: userPrompt.RequestID,
+		Persona:   userPrompt.Persona,
+		Client:    userPrompt.Client,
+	})
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(answer.Text)), "YES"), nil
+}
+
+// keywordStems splits comma separated keywords, each keyword may be a phrase
+func keywordStems(keywords string) [][]string {
+	var result [][]string
+	for _, keyword := range strings.Split(keywords, ",") {
+		var phrase []string
+		for _, word := range tokenize(keyword) {
+			phrase = append(phrase, stem(word))
+		}
+		if len(phrase) > 0 {
+			result = append(result, phrase)
+		}
+	}
+	return result
+}
+
+func containsAll(set map[string]struct{}, words []string) bool {
+	for _, word := range words {
+		if _, ok := set[word]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,21 @@
+package history
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// charsPerToken rough average for English text
+const charsPerToken = 4
+
+// Estimator counts tokens locally without calling the provider
+type Estimator struct{}
+
+// CountTokens estimates the number of tokens of the texts
+func (Estimator) CountTokens(ctx context.Context, texts []string) (int, error) {
+	tokens := 0
+	for _, text := range texts {
+		tokens += (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+	}
+	return tokens, nil
+}
@@ -0,0 +1,106 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	TokenCounterProvider = "provider"
+	TokenCounterEstimate = "estimate"
+
+	keepTurnsDefault = 4
+)
+
+// Manager keeps the history sent with each prompt within the configured token budget
+// by summarizing the oldest turns
+type Manager struct {
+	cfg        *config.Config
+	log        *logrus.Entry
+	counter    domain.TokenCounter
+	summarizer domain.LanguageModelService
+}
+
+// NewManager init new history manager, summarizer is only called once the threshold is reached
+func NewManager(cfg *config.Config, log *logrus.Entry, counter domain.TokenCounter, summarizer domain.LanguageModelService) *Manager {
+	return &Manager{
+		cfg:        cfg,
+		log:        log,
+		counter:    counter,
+		summarizer: summarizer,
+	}
+}
+
+// Compact summarizes the oldest active turns of the session when its history exceeds the threshold,
+// it returns whether the session changed
+func (m *Manager) Compact(ctx context.Context, session *domain.Session, requestID string) (bool, error) {
+	active := session.ActiveTurns()
+	if m.cfg.History.MaxTokens <= 0 || len(active) <= m.keepTurns() {
+		return false, nil
+	}
+
+	tokens, err := m.counter.CountTokens(ctx, historyTexts(session.Summary, active))
+	if err != nil {
+		return false, err
+	}
+	log := logger.ExtractOr(ctx, m.log).WithFields(logrus.Fields{
+		"history.tokens":     tokens,
+		"history.max_tokens": m.cfg.History.MaxTokens,
+	})
+	if tokens <= m.cfg.History.MaxTokens {
+		log.Debugln("history.compact.skipped")
+		return false, nil
+	}
+
+	oldest := active[:len(active)-m.keepTurns()]
+	resp, err := m.summarizer.GenerateResponse(ctx, domain.UserPrompt{
+		Text:      fmt.Sprintf(domain.SUMMARY_PROMPT, transcript(session.Summary, oldest)),
+		RequestID: requestID,
+		Persona:   session.Persona,
+		Client:    session.Client,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	session.Summary = strings.TrimSpace(resp.Text)
+	session.SummarizedTurns += len(oldest)
+	log.WithField("history.summarized_turns", session.SummarizedTurns).Infoln("history.compact.summarized")
+
+	return true, nil
+}
+
+func (m *Manager) keepTurns() int {
+	if m.cfg.History.KeepTurns > 0 {
+		return m.cfg.History.KeepTurns
+	}
+	return keepTurnsDefault
+}
+
+func historyTexts(summary string, turns []domain.Turn) []string {
+	texts := make([]string, 0, len(turns)*2+1)
+	if summary != "" {
+		texts = append(texts, summary)
+	}
+	for _, turn := range turns {
+		texts = append(texts, turn.Prompt, turn.Response)
+	}
+	return texts
+}
+
+func transcript(summary string, turns []domain.Turn) string {
+	var b strings.Builder
+	if summary != "" {
+		fmt.Fprintf(&b, "Earlier summary: %s\n\n", summary)
+	}
+	for _, turn := range turns {
+		fmt.Fprintf(&b, "User: %s\nCoach: %s\n\n", turn.Prompt, turn.Response)
+	}
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package languagemodels
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/caching"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/tracing"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// cachedService serves saved responses and only caches responses that passed every inner stage
+type cachedService struct {
+	cfg   *config.Config
+	log   *logrus.Entry
+	cache caching.Cache
+	next  domain.LanguageModelService
+}
+
+func newCachedService(cfg *config.Config, log *logrus.Entry, cache caching.Cache, next domain.LanguageModelService) domain.LanguageModelService {
+	return &cachedService{
+		cfg:   cfg,
+		log:   log,
+		cache: cache,
+		next:  next,
+	}
+}
+
+// GenerateResponse checks cache for saved responses (if any), calls the next stage for uncached responses
+func (s *cachedService) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	key := cacheKey(userPrompt)
+	if !userPrompt.SkipCache {
+		_, span := tracing.Start(ctx, "cache.get")
+		strResp, exists := s.cache.Get(key)
+		span.SetAttributes(attribute.Bool("promptme.cache.hit", exists))
+		span.End()
+		if exists {
+			logger.ExtractOr(ctx, s.log).Infoln("getcache.exists.value:", strResp)
+			if userPrompt.Stream != nil {
+				userPrompt.Stream(strResp)
+			}
+			return s.cachedResponse(userPrompt, strResp), nil
+		}
+	}
+
+	resp, err := s.next.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		return domain.Response{}, err
+	}
+
+	logger.ExtractOr(ctx, s.log).Infoln("setcache.newresponse.set")
+	s.cache.Set(key, resp.Text)
+
+	return resp, nil
+}
+
+// cachedResponse the cached text with the provider and model it was generated by, the key includes the model
+func (s *cachedService) cachedResponse(userPrompt domain.UserPrompt, text string) domain.Response {
+	model := userPrompt.Model
+	if model == "" {
+		model = s.cfg.LLM.Gemini.Model
+	}
+	return domain.Response{
+		Text:     text,
+		Provider: s.cfg.LLM.Provider,
+		Model:    model,
+		Cached:   true,
+	}
+}
+
+// cacheKey is the prompt with a hash of everything else the response depends on: the persona, whose
+// keywords and system instruction differ, the model, the instruction and the conversation history.
+// Follow-up prompts are only answered from cache within the same conversation history
+func cacheKey(userPrompt domain.UserPrompt) string {
+	h := sha256.New()
+	h.Write([]byte(userPrompt.Persona))
+	h.Write([]byte{0})
+	h.Write([]byte(userPrompt.Model))
+	h.Write([]byte{0})
+	h.Write([]byte(userPrompt.Instruction))
+	h.Write([]byte{0})
+	h.Write([]byte(userPrompt.Summary))
+	h.Write([]byte{0})
+	for _, turn := range userPrompt.History {
+		h.Write([]byte(turn.Prompt))
+		h.Write([]byte{0})
+		h.Write([]byte(turn.Response))
+		h.Write([]byte{0})
+	}
+	return userPrompt.Text + " #" + hex.EncodeToString(h.Sum(nil))
+}
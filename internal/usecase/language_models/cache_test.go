@@ -0,0 +1,69 @@
+package languagemodels
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/caching"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/sirupsen/logrus"
+)
+
+// stubService answers every prompt with the same response
+type stubService struct {
+	resp  domain.Response
+	calls int
+}
+
+func (s *stubService) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	s.calls++
+	return s.resp, nil
+}
+
+func TestCachedServiceHitHasProviderAndModel(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LLM.Provider = domain.LLM_PROVIDER_GEMINI
+	cfg.LLM.Gemini.Model = "gemini-1.5-flash"
+
+	tests := []struct {
+		name      string
+		model     string
+		wantModel string
+	}{
+		{"configured model", "", "gemini-1.5-flash"},
+		{"requested model", "gemini-1.5-pro", "gemini-1.5-pro"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &stubService{resp: domain.Response{
+				Text:     "Ask for a meeting.",
+				Provider: domain.LLM_PROVIDER_GEMINI,
+				Model:    tt.wantModel,
+			}}
+			srv := newCachedService(cfg, logrus.NewEntry(logrus.New()), caching.NewInMemory(cfg), next)
+			userPrompt := domain.UserPrompt{Text: "How do I ask for a raise?", Persona: domain.PERSONA_CAREER, Model: tt.model}
+
+			if _, err := srv.GenerateResponse(context.Background(), userPrompt); err != nil {
+				t.Fatalf("GenerateResponse: %v", err)
+			}
+			resp, err := srv.GenerateResponse(context.Background(), userPrompt)
+			if err != nil {
+				t.Fatalf("GenerateResponse: %v", err)
+			}
+
+			if next.calls != 1 {
+				t.Errorf("next called %d times, want 1", next.calls)
+			}
+			if !resp.Cached {
+				t.Errorf("Cached = false, want true")
+			}
+			if resp.Provider != domain.LLM_PROVIDER_GEMINI {
+				t.Errorf("Provider = %q, want %q", resp.Provider, domain.LLM_PROVIDER_GEMINI)
+			}
+			if resp.Model != tt.wantModel {
+				t.Errorf("Model = %q, want %q", resp.Model, tt.wantModel)
+			}
+		})
+	}
+}
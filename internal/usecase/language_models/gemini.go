@@ -2,13 +2,17 @@ package languagemodels
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/aisalamdag23/promptme-cli/internal/domain"
-	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/caching"
 	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/logger"
 	ratelimit "github.com/aisalamdag23/promptme-cli/internal/infrastructure/rate_limit"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/iterator"
@@ -18,79 +22,208 @@ type geminiService struct {
 	cfg     *config.Config
 	log     *logrus.Entry
 	client  *genai.Client
-	cache   caching.Cache
 	limiter *ratelimit.RateLimiter
+	// instruction is the rendered topic restriction, empty if unrestricted
+	instruction string
 }
 
-// GenerateResponse checks cache for saved responses (if any), send message to gemini for uncached responses
-func (s *geminiService) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (string, error) {
-	// check cache
-	strResp, exists := s.cache.Get(userPrompt.Text)
-	if exists {
-		s.log.Infoln("getcache.exists.value:", strResp)
-		return strResp, nil
-	}
+// GenerateResponse sends the prompt to gemini
+func (s *geminiService) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (resp domain.Response, err error) {
+	ctx, span := tracing.Start(ctx, "gemini.generate", attribute.String("gen_ai.system", domain.LLM_PROVIDER_GEMINI))
+	defer func() { tracing.End(span, err) }()
 
-	// start lm api
-	strResp, err := s.generateGeminiResponse(ctx, userPrompt.Text)
+	resp, err = s.generateGeminiResponse(ctx, userPrompt)
 	if err != nil {
-		s.log.Errorln("generategeminiresponse.failed", err)
-		return "", err
+		logger.ExtractOr(ctx, s.log).Errorln("generategeminiresponse.failed", err)
+		return domain.Response{}, err
 	}
+	span.SetAttributes(
+		attribute.String("gen_ai.request.model", resp.Model),
+		attribute.String("gen_ai.response.finish_reason", resp.FinishReason),
+		attribute.Int("gen_ai.usage.input_tokens", resp.Usage.PromptTokens),
+		attribute.Int("gen_ai.usage.output_tokens", resp.Usage.CompletionTokens),
+	)
 
-	s.log.Infoln("setcache.newresponse.set")
-	s.cache.Set(userPrompt.Text, strResp)
+	logger.ExtractOr(ctx, s.log).WithFields(logrus.Fields{
+		"finish_reason":     resp.FinishReason,
+		"prompt_tokens":     resp.Usage.PromptTokens,
+		"completion_tokens": resp.Usage.CompletionTokens,
+	}).Infoln("generategeminiresponse.done")
 
-	return strResp, nil
+	return resp, nil
 }
 
-// initKeywords init topics that the gemini response will only answer
-func (s *geminiService) initKeywords() []*genai.Content {
-	if s.cfg.LLM.Keywords != "" {
-		s.log.Infoln("keyword.restriction.on:", s.cfg.LLM.Keywords)
-		return []*genai.Content{
-			{
-				Parts: []genai.Part{
-					genai.Text(fmt.Sprintf(domain.LLM_RESPONSE_CONTEXT, s.cfg.LLM.Keywords)),
-				},
-				Role: "user",
-			},
-		}
+// CountTokens counts the tokens of the texts with gemini's count tokens endpoint
+func (s *geminiService) CountTokens(ctx context.Context, texts []string) (int, error) {
+	parts := make([]genai.Part, 0, len(texts))
+	for _, text := range texts {
+		parts = append(parts, genai.Text(text))
+	}
+
+	resp, err := s.client.GenerativeModel(s.cfg.LLM.Gemini.Model).CountTokens(ctx, parts...)
+	if err != nil {
+		logger.ExtractOr(ctx, s.log).Errorln("gemini.counttokens.failed", err)
+		return 0, err
+	}
+	return int(resp.TotalTokens), nil
+}
+
+// initSystemInstruction sets the topics that the gemini response will only answer
+// extra is appended for this prompt only, e.g. a stricter retry instruction
+func (s *geminiService) initSystemInstruction(model *genai.GenerativeModel, extra string) {
+	instruction := composeInstruction(s.instruction, extra)
+	if instruction == "" {
+		s.log.Infoln("keyword.restriction.off")
+		return
+	}
+
+	s.log.Infoln("keyword.restriction.on:", s.cfg.LLM.Keywords)
+	model.SystemInstruction = &genai.Content{
+		Parts: []genai.Part{genai.Text(instruction)},
 	}
-	s.log.Infoln("keyword.restriction.off")
-	return []*genai.Content{}
 }
 
-func (s *geminiService) generateGeminiResponse(ctx context.Context, strPrompt string) (string, error) {
-	err := s.limiter.Do(ctx)
+// composeInstruction puts the prompt's instruction, which may come from an API client, before the topic restriction
+// so that the restriction is the last word and cannot be lifted by it
+func composeInstruction(restriction, extra string) string {
+	return strings.TrimSpace(strings.TrimSpace(extra) + "\n\n" + restriction)
+}
+
+func (s *geminiService) generateGeminiResponse(ctx context.Context, userPrompt domain.UserPrompt) (_ domain.Response, err error) {
+	err = s.limiter.Do(ctx)
 	if err != nil {
-		s.log.Errorln("ratelimiter.do.failed", err)
-		return "", err
+		logger.ExtractOr(ctx, s.log).Errorln("ratelimiter.do.failed", err)
+		return domain.Response{}, err
 	}
 
-	model := s.client.GenerativeModel(s.cfg.LLM.Gemini.Model)
+	modelName := s.cfg.LLM.Gemini.Model
+	if userPrompt.Model != "" {
+		modelName = userPrompt.Model
+	}
+	model := s.client.GenerativeModel(modelName)
+	s.initSystemInstruction(model, userPrompt.Instruction)
 	cs := model.StartChat()
-	cs.History = s.initKeywords()
-	iter := cs.SendMessageStream(context.Background(), genai.Text(strPrompt))
-	strResp := ""
+	cs.History = geminiHistory(userPrompt.Summary, userPrompt.History)
+
+	// the request is sent by the first call to Next, the stream span covers it and every chunk
+	ctx, span := tracing.Start(ctx, "gemini.stream")
+	defer func() { tracing.End(span, err) }()
+	chunks := 0
+	defer func() { span.SetAttributes(attribute.Int("promptme.chunks", chunks)) }()
+
+	iter := cs.SendMessageStream(ctx, genai.Text(userPrompt.Text))
+	result := domain.Response{
+		Provider: domain.LLM_PROVIDER_GEMINI,
+		Model:    modelName,
+	}
 	for {
 		resp, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
+		var blocked *genai.BlockedError
+		if errors.As(err, &blocked) {
+			err = geminiBlockedError(blocked)
+			return domain.Response{}, err
+		}
 		if err != nil {
-			return "", err
+			return domain.Response{}, err
 		}
-		if resp != nil {
-			for _, cand := range resp.Candidates {
-				if cand.Content != nil {
-					for _, part := range cand.Content.Parts {
-						strResp += fmt.Sprintf("%s", part)
+		if resp == nil {
+			continue
+		}
+		for _, cand := range resp.Candidates {
+			if cand.Content != nil {
+				for _, part := range cand.Content.Parts {
+					chunk := fmt.Sprintf("%s", part)
+					if chunks == 0 {
+						span.AddEvent("first_chunk")
+					}
+					chunks++
+					result.Text += chunk
+					if userPrompt.Stream != nil {
+						userPrompt.Stream(chunk)
 					}
 				}
 			}
+			if cand.FinishReason != genai.FinishReasonUnspecified {
+				result.FinishReason = geminiFinishReason(cand.FinishReason)
+			}
+			if len(cand.SafetyRatings) > 0 {
+				result.SafetyRatings = geminiSafetyRatings(cand.SafetyRatings)
+			}
 		}
+		// usage metadata is cumulative, the last chunk has the totals
+		if resp.UsageMetadata != nil {
+			result.Usage = domain.Usage{
+				PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
+				CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+				TotalTokens:      int(resp.UsageMetadata.TotalTokenCount),
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// geminiHistory converts the summary and previous turns into alternating user and model contents
+func geminiHistory(summary string, turns []domain.Turn) []*genai.Content {
+	history := make([]*genai.Content, 0, len(turns)*2+2)
+	if summary != "" {
+		history = append(history,
+			&genai.Content{Role: "user", Parts: []genai.Part{genai.Text(fmt.Sprintf(domain.SUMMARY_CONTEXT, summary))}},
+			&genai.Content{Role: "model", Parts: []genai.Part{genai.Text(domain.SUMMARY_ACK)}},
+		)
 	}
+	for _, turn := range turns {
+		history = append(history,
+			&genai.Content{Role: "user", Parts: []genai.Part{genai.Text(turn.Prompt)}},
+			&genai.Content{Role: "model", Parts: []genai.Part{genai.Text(turn.Response)}},
+		)
+	}
+	return history
+}
 
-	return strResp, nil
+func geminiSafetyRatings(ratings []*genai.SafetyRating) []domain.SafetyRating {
+	result := make([]domain.SafetyRating, 0, len(ratings))
+	for _, rating := range ratings {
+		result = append(result, domain.SafetyRating{
+			Category:    rating.Category.String(),
+			Probability: rating.Probability.String(),
+			Blocked:     rating.Blocked,
+		})
+	}
+	return result
+}
+
+// geminiFinishReason maps the gemini finish reason to the domain one
+func geminiFinishReason(reason genai.FinishReason) string {
+	switch reason {
+	case genai.FinishReasonStop:
+		return domain.FINISH_REASON_STOP
+	case genai.FinishReasonMaxTokens:
+		return domain.FINISH_REASON_MAX_TOKENS
+	case genai.FinishReasonSafety:
+		return domain.FINISH_REASON_SAFETY
+	case genai.FinishReasonRecitation:
+		return domain.FINISH_REASON_RECITATION
+	default:
+		return domain.FINISH_REASON_OTHER
+	}
+}
+
+// geminiBlockedError converts a safety block into a rejection shown to the user
+func geminiBlockedError(blocked *genai.BlockedError) error {
+	reason := "blocked"
+	if blocked.PromptFeedback != nil {
+		reason = "prompt:" + blocked.PromptFeedback.BlockReason.String()
+	}
+	if blocked.Candidate != nil {
+		reason = "candidate:" + geminiFinishReason(blocked.Candidate.FinishReason)
+	}
+	return &domain.RejectionError{
+		Stage:   "provider_safety",
+		Reason:  reason,
+		Message: domain.SAFETY_BLOCKED_MESSAGE,
+	}
 }
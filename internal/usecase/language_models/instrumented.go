@@ -0,0 +1,78 @@
+package languagemodels
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/metrics"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// instrumentedService records the outcome, latency and time to first token of every request,
+// and traces it in a span parent of the spans of the inner stages
+type instrumentedService struct {
+	next domain.LanguageModelService
+}
+
+func newInstrumentedService(next domain.LanguageModelService) domain.LanguageModelService {
+	return &instrumentedService{next: next}
+}
+
+// GenerateResponse calls the next stage and records its metrics
+func (s *instrumentedService) GenerateResponse(ctx context.Context, userPrompt domain.UserPrompt) (domain.Response, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "languagemodel.generate",
+		attribute.String("promptme.request_id", userPrompt.RequestID),
+		attribute.String("promptme.persona", userPrompt.Persona),
+		attribute.String("promptme.client", userPrompt.Client),
+	)
+
+	// the first chunk is what the user waits for, the stream is observed even when nobody reads it
+	var firstChunk sync.Once
+	stream := userPrompt.Stream
+	userPrompt.Stream = func(chunk string) {
+		firstChunk.Do(func() {
+			span.AddEvent("first_token")
+			metrics.TimeToFirstToken.WithLabelValues(userPrompt.Persona).Observe(time.Since(start).Seconds())
+		})
+		if stream != nil {
+			stream(chunk)
+		}
+	}
+
+	resp, err := s.next.GenerateResponse(ctx, userPrompt)
+	if err != nil {
+		outcome, class := errorClass(err)
+		metrics.Requests.WithLabelValues(userPrompt.Persona, outcome).Inc()
+		metrics.Errors.WithLabelValues(class).Inc()
+		span.SetAttributes(attribute.String("promptme.outcome", outcome))
+		tracing.End(span, err)
+		return resp, err
+	}
+
+	metrics.Requests.WithLabelValues(userPrompt.Persona, "ok").Inc()
+	metrics.RequestDuration.WithLabelValues(userPrompt.Persona, strconv.FormatBool(resp.Cached)).Observe(time.Since(start).Seconds())
+	span.SetAttributes(attribute.String("promptme.outcome", "ok"), attribute.Bool("promptme.cached", resp.Cached))
+	tracing.End(span, nil)
+	return resp, nil
+}
+
+// errorClass returns the outcome and class of a failed request, rejections are classed by their stage
+func errorClass(err error) (string, string) {
+	var rejection *domain.RejectionError
+	switch {
+	case errors.As(err, &rejection):
+		return "rejected", rejection.Stage
+	case errors.Is(err, context.Canceled):
+		return "error", "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "error", "timeout"
+	default:
+		return "error", "provider"
+	}
+}
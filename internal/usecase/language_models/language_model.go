@@ -3,39 +3,213 @@ package languagemodels
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/aisalamdag23/promptme-cli/internal/domain"
 	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/caching"
 	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/httpclient"
 	ratelimit "github.com/aisalamdag23/promptme-cli/internal/infrastructure/rate_limit"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/billing"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/guardrails"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/history"
 	"github.com/google/generative-ai-go/genai"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"golang.org/x/time/rate"
+	"google.golang.org/api/googleapi/transport"
 	"google.golang.org/api/option"
 )
 
 // NewGenerator determines which service to create based on the configuration/provider
-func NewGenerator(ctx context.Context, cfg *config.Config, log *logrus.Entry, cache caching.Cache) (domain.LanguageModelService, error) {
+// and wraps it with the cache and the configured guardrails:
+// metrics and tracing -> redaction -> topic guard -> cache -> budget -> moderation -> usage recorder -> provider
+func NewGenerator(ctx context.Context, cfg *config.Config, log *logrus.Entry, cache caching.Cache, usageLedger *billing.Ledger) (domain.LanguageModelService, error) {
+	return newGenerator(ctx, cfg, log, cache, usageLedger, nil)
+}
+
+// newGenerator composes the generator, the provider and the utility providers of the guardrails
+// share the given limiter or a new one when nil
+func newGenerator(ctx context.Context, cfg *config.Config, log *logrus.Entry, cache caching.Cache, usageLedger *billing.Ledger, limiter *ratelimit.RateLimiter) (domain.LanguageModelService, error) {
+	if limiter == nil {
+		limiter = NewRateLimiter(cfg, log)
+	}
+	srv, err := newProvider(ctx, cfg, log, limiter)
+	if err != nil {
+		return nil, err
+	}
+	srv = billing.NewRecorder(cfg, log, usageLedger, srv)
+
+	var judge domain.LanguageModelService
+	if cfg.Guardrails.Moderation.Enabled && cfg.Guardrails.Moderation.JudgeModel != "" {
+		judge, err = newUtilityProvider(ctx, cfg, log, usageLedger, limiter, cfg.Guardrails.Moderation.JudgeModel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	srv, err = guardrails.NewModeration(cfg, log, srv, judge)
+	if err != nil {
+		log.Errorln("guardrails.newmoderation.failed:", err)
+		return nil, err
+	}
+
+	srv = billing.NewBudgetGuard(cfg, log, usageLedger, srv)
+	srv = newCachedService(cfg, log, cache, srv)
+
+	var classifier domain.LanguageModelService
+	if cfg.Guardrails.Topic.Enabled && cfg.Guardrails.Topic.ClassifierModel != "" {
+		classifier, err = newUtilityProvider(ctx, cfg, log, usageLedger, limiter, cfg.Guardrails.Topic.ClassifierModel)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	srv, err = guardrails.NewTopicGuard(cfg, log, srv, classifier)
+	if err != nil {
+		log.Errorln("guardrails.newtopicguard.failed:", err)
+		return nil, err
+	}
+
+	redactor, err := guardrails.NewRedactor(cfg)
+	if err != nil {
+		log.Errorln("guardrails.newredactor.failed:", err)
+		return nil, err
+	}
+
+	srv = guardrails.NewRedaction(cfg, log, srv, redactor)
+	return newInstrumentedService(srv), nil
+}
+
+// newUtilityProvider creates an unrestricted provider for the given model, used by classifiers and judges,
+// its requests wait on the limiter of the main provider and count against the same budgets
+func newUtilityProvider(ctx context.Context, cfg *config.Config, log *logrus.Entry, usageLedger *billing.Ledger, limiter *ratelimit.RateLimiter, model string) (domain.LanguageModelService, error) {
+	utilityCfg := *cfg
+	utilityCfg.LLM.Keywords = ""
+	utilityCfg.LLM.Gemini.Model = model
+	srv, err := newProvider(ctx, &utilityCfg, log, limiter)
+	if err != nil {
+		return nil, err
+	}
+	srv = billing.NewRecorder(cfg, log, usageLedger, srv)
+	return billing.NewBudgetGuard(cfg, log, usageLedger, srv), nil
+}
+
+// newProvider creates the language model service of the configured provider,
+// requests wait on the given limiter or on a new one when nil
+func newProvider(ctx context.Context, cfg *config.Config, log *logrus.Entry, limiter *ratelimit.RateLimiter) (domain.LanguageModelService, error) {
 	log.Infoln("newgenerator.provider:", cfg.LLM.Provider)
 	// Check the model provider in the config
 	switch cfg.LLM.Provider {
 	case domain.LLM_PROVIDER_GEMINI:
-		client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.General.APIKey))
+		instruction, err := systemInstruction(cfg)
+		if err != nil {
+			log.Errorln("systeminstruction.failed:", err)
+			return nil, err
+		}
+
+		if limiter == nil {
+			limiter = NewRateLimiter(cfg, log)
+		}
+
+		// the api key is only used by clients without the http client option, e.g. the cache client
+		client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.General.APIKey), option.WithHTTPClient(newGeminiHTTPClient(cfg, log)))
 		if err != nil {
 			log.Errorln("genai.newclient.failed:", err)
 			return nil, err
 		}
 
 		return &geminiService{
-			cfg:     cfg,
-			log:     log,
-			client:  client,
-			cache:   cache,
-			limiter: ratelimit.NewRateLimiter(log, rate.Every(time.Minute), cfg.LLM.Gemini.MaxRequestsPerMinute),
+			cfg:         cfg,
+			log:         log,
+			client:      client,
+			limiter:     limiter,
+			instruction: instruction,
 		}, nil
 
 	default:
 		return nil, fmt.Errorf("unsupported language model provider")
 	}
 }
+
+// newGeminiHTTPClient logs and traces the gemini requests, the api key is added to the url
+// after both so that neither the logs nor the spans contain it
+func newGeminiHTTPClient(cfg *config.Config, log *logrus.Entry) *http.Client {
+	keyTransport := &transport.APIKey{
+		Key:       cfg.General.APIKey,
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+	return &http.Client{
+		Transport: otelhttp.NewTransport(httpclient.NewTransport(domain.LLM_PROVIDER_GEMINI, log, keyTransport, cfg.General.APIKey)),
+	}
+}
+
+// NewRateLimiter creates the limiter of the configured provider's requests per minute
+func NewRateLimiter(cfg *config.Config, log *logrus.Entry) *ratelimit.RateLimiter {
+	return ratelimit.NewRateLimiter(log, rate.Every(time.Minute), cfg.LLM.Gemini.MaxRequestsPerMinute)
+}
+
+// newHistoryManager creates the history manager with the configured token counter,
+// summaries are generated by an unrestricted model and go through PII redaction
+func newHistoryManager(ctx context.Context, cfg *config.Config, log *logrus.Entry, usageLedger *billing.Ledger, limiter *ratelimit.RateLimiter) (*history.Manager, error) {
+	model := cfg.History.SummaryModel
+	if model == "" {
+		model = cfg.LLM.Gemini.Model
+	}
+	summarizer, err := newUtilityProvider(ctx, cfg, log, usageLedger, limiter, model)
+	if err != nil {
+		return nil, err
+	}
+
+	redactor, err := guardrails.NewRedactor(cfg)
+	if err != nil {
+		log.Errorln("guardrails.newredactor.failed:", err)
+		return nil, err
+	}
+	summarizer = guardrails.NewRedaction(cfg, log, summarizer, redactor)
+
+	var counter domain.TokenCounter = history.Estimator{}
+	if cfg.History.TokenCounter == history.TokenCounterProvider {
+		srv, err := newProvider(ctx, cfg, log, limiter)
+		if err != nil {
+			return nil, err
+		}
+		providerCounter, ok := srv.(domain.TokenCounter)
+		if ok {
+			counter = guardrails.NewRedactingCounter(cfg, providerCounter, redactor)
+		} else {
+			log.Warnln("newhistorymanager.tokencounter.unsupported:", cfg.LLM.Provider)
+		}
+	}
+
+	return history.NewManager(cfg, log, counter, summarizer), nil
+}
+
+// systemInstruction renders the topic restriction that providers send as their native system instruction,
+// an empty string means no restriction
+func systemInstruction(cfg *config.Config) (string, error) {
+	if cfg.LLM.Keywords == "" {
+		return "", nil
+	}
+
+	text := cfg.LLM.SystemInstruction
+	if text == "" {
+		text = domain.LLM_RESPONSE_CONTEXT
+	}
+
+	tpl, err := template.New("system_instruction").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid system instruction template: %w", err)
+	}
+
+	var b strings.Builder
+	err = tpl.Execute(&b, struct{ Keywords string }{Keywords: cfg.LLM.Keywords})
+	if err != nil {
+		return "", fmt.Errorf("invalid system instruction template: %w", err)
+	}
+
+	return b.String(), nil
+}
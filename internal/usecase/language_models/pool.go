@@ -0,0 +1,63 @@
+package languagemodels
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aisalamdag23/promptme-cli/internal/domain"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/caching"
+	"github.com/aisalamdag23/promptme-cli/internal/infrastructure/config"
+	ratelimit "github.com/aisalamdag23/promptme-cli/internal/infrastructure/rate_limit"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/billing"
+	"github.com/aisalamdag23/promptme-cli/internal/usecase/history"
+	"github.com/sirupsen/logrus"
+)
+
+// GeneratorPool creates one generator per persona and reuses it for every request,
+// all of them share the cache, the usage ledger and the provider rate limiter.
+// Request scoped fields are logged through the logger in the request context.
+type GeneratorPool struct {
+	ctx         context.Context
+	cfg         *config.Config
+	log         *logrus.Entry
+	cache       caching.Cache
+	usageLedger *billing.Ledger
+	limiter     *ratelimit.RateLimiter
+
+	mu         sync.Mutex
+	generators map[string]domain.LanguageModelService
+}
+
+func NewGeneratorPool(ctx context.Context, cfg *config.Config, log *logrus.Entry, cache caching.Cache, usageLedger *billing.Ledger) *GeneratorPool {
+	return &GeneratorPool{
+		ctx:         ctx,
+		cfg:         cfg,
+		log:         log,
+		cache:       cache,
+		usageLedger: usageLedger,
+		limiter:     NewRateLimiter(cfg, log),
+		generators:  make(map[string]domain.LanguageModelService),
+	}
+}
+
+// NewHistoryManager creates the history manager, its summarizer and token counter share the pool's rate limiter
+func (p *GeneratorPool) NewHistoryManager() (*history.Manager, error) {
+	return newHistoryManager(p.ctx, p.cfg, p.log, p.usageLedger, p.limiter)
+}
+
+// Get returns the generator of the persona, creating it on first use
+func (p *GeneratorPool) Get(persona string) (domain.LanguageModelService, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if srv, ok := p.generators[persona]; ok {
+		return srv, nil
+	}
+
+	srv, err := newGenerator(p.ctx, p.cfg.ForPersona(persona), p.log, p.cache, p.usageLedger, p.limiter)
+	if err != nil {
+		return nil, err
+	}
+	p.generators[persona] = srv
+	return srv, nil
+}